@@ -0,0 +1,78 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// setupAliases gives every alias configured under aliases the value of
+// the variable it stands for, e.g.
+//
+//	aliases:
+//	  MYSQL_HOST: DB_HOST
+//
+// so code expecting either name sees the same value during a migration.
+// When only the alias is set, as in a dotenv file predating the rename,
+// the variable gets the alias's value instead. Viper lower cases config
+// keys, so the names are upper cased.
+func setupAliases() error {
+
+	aliases := make(map[string]string)
+	for alias, name := range viper.GetStringMapString("aliases") {
+		alias, name = strings.ToUpper(alias), strings.ToUpper(name)
+		if !keyNameRe.MatchString(alias) || !keyNameRe.MatchString(name) {
+			return fmt.Errorf("aliases.%s: %q and %q must both be variable names", alias, alias, name)
+		}
+		aliases[alias] = name
+	}
+
+	var names []string
+	for alias, name := range aliases {
+		if _, ok := aliases[name]; ok {
+			return fmt.Errorf("aliases.%s: %s is an alias itself, point %s at the variable it stands for", alias, name, alias)
+		}
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+
+	for _, alias := range names {
+		name := aliases[alias]
+		value, set := os.LookupEnv(name)
+		aliasValue, aliasSet := os.LookupEnv(alias)
+
+		switch {
+		case set && aliasSet && value != aliasValue:
+			warn("%s is an alias of %s but set to a different value, using the value of %s\n", alias, name, name)
+		case !set && aliasSet:
+			if err := setEnv(name, aliasValue); err != nil {
+				return err
+			}
+			continue
+		case !set:
+			continue
+		}
+
+		if err := setEnv(alias, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// allowListFile, in the home directory, maps the directories allowed with
+// loadenv allow to the digests their config and dotenv files had when they
+// were allowed, separated by a space.
+const allowListFile = ".loadenv/allowed.json"
+
+// NewAllowCmd returns the allow command.
+func NewAllowCmd() *cobra.Command {
+
+	allowCmd := &cobra.Command{
+		Use:   "allow [dir]",
+		Short: "Trust the config of a project directory",
+		Long: `Allow marks the ` + projectConfigFile() + ` of the directory, the local one by
+default, as reviewed. Until then loadenv refuses to run anything that
+config makes it execute on its own, such as the rego policies of
+policy.files, which are handed every value, and the shell hook does not
+load its dotenv file into the shell. Changing the config revokes the
+approval, so it has to be reviewed and allowed again. So does changing the
+dotenv file or one of its layers, e.g. .env.local, for the shell hook,
+as a changed PATH or other variable would take effect in the shell. The
+changes loadenv makes itself, with set or to keep generated values, keep
+the approval.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := setAllowed(args, true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return allowCmd
+}
+
+// NewDenyCmd returns the deny command.
+func NewDenyCmd() *cobra.Command {
+
+	denyCmd := &cobra.Command{
+		Use:   "deny [dir]",
+		Short: "Revoke the trust given with allow",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := setAllowed(args, false); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return denyCmd
+}
+
+// allowListPath returns the path of the allow list.
+func allowListPath() (string, error) {
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, allowListFile), nil
+}
+
+// readAllowList returns the allowed directories and their config digests.
+func readAllowList() (map[string]string, error) {
+
+	fname, err := allowListPath()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]string)
+
+	b, err := os.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return allowed, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &allowed); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+
+	return allowed, nil
+}
+
+// configDigest returns the digest of the project configs in dir, every
+// file viper could read as the config, e.g. .loadenv.json before
+// .loadenv.yaml, that of no config when there is none, so a directory
+// allowed before it had a config has to be allowed again once it has one.
+func configDigest(dir string) (string, error) {
+
+	var b strings.Builder
+	for _, ext := range viper.SupportedExts {
+		fname := flags.configName + "." + ext
+		content, err := os.ReadFile(filepath.Join(dir, fname))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s\x00%d\x00%s", fname, len(content), content)
+	}
+
+	return digest(b.String()), nil
+}
+
+// dotenvDigest returns the digest of the dotenv files in dir that the
+// environment can be loaded from: the dotenv file, its layers for any
+// environment, and further --dotenv files.
+func dotenvDigest(dir string) (string, error) {
+
+	fnames, err := filepath.Glob(filepath.Join(dir, filepath.Base(dotenvFileName())) + "*")
+	if err != nil {
+		return "", err
+	}
+	for _, fname := range flags.dotenvFiles {
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(dir, fname)
+		}
+		if !hasString(fnames, fname) {
+			fnames = append(fnames, fname)
+		}
+	}
+	sort.Strings(fnames)
+
+	var b strings.Builder
+	for _, fname := range fnames {
+		// the example is not loaded, and is regenerated often
+		if strings.HasSuffix(fname, ".example") {
+			continue
+		}
+		content, err := os.ReadFile(fname)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s\x00%d\x00%s", filepath.Base(fname), len(content), content)
+	}
+
+	return digest(b.String()), nil
+}
+
+// allowedDigest returns what the allow list records for dir when it is
+// allowed as it is now.
+func allowedDigest(dir string) (string, error) {
+
+	config, err := configDigest(dir)
+	if err != nil {
+		return "", err
+	}
+
+	dotenv, err := dotenvDigest(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return config + " " + dotenv, nil
+}
+
+// setAllowed allows or denies the directory in args, the local one by
+// default.
+func setAllowed(args []string, allow bool) error {
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	dir, err := realPath(dir)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := readAllowList()
+	if err != nil {
+		return err
+	}
+
+	if allow {
+		d, err := allowedDigest(dir)
+		if err != nil {
+			return err
+		}
+		allowed[dir] = d
+	} else {
+		delete(allowed, dir)
+	}
+
+	if err := writeAllowList(allowed); err != nil {
+		return err
+	}
+
+	if allow {
+		info("Allowed %s\n", dir)
+	} else {
+		info("Denied %s\n", dir)
+	}
+
+	return nil
+}
+
+// writeAllowList replaces the allow list with allowed.
+func writeAllowList(allowed map[string]string) error {
+
+	fname, err := allowListPath()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(allowed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fname), 0700); err != nil {
+		return err
+	}
+
+	tmp := fname + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, fname)
+}
+
+// dirAllowed reports whether dir was allowed with its config and dotenv
+// files as they are now.
+func dirAllowed(dir string) (bool, error) {
+
+	allowed, err := readAllowList()
+	if err != nil {
+		return false, err
+	}
+
+	d, err := allowedDigest(dir)
+	if err != nil {
+		return false, err
+	}
+
+	return allowed[dir] == d, nil
+}
+
+// keepAllowed runs write, which changes dotenv files of the local
+// directory, and allows the directory again when it was allowed before,
+// as the change is loadenv's own.
+func keepAllowed(write func() error) error {
+
+	dir, err := realPath(".")
+	if err != nil {
+		return err
+	}
+
+	was, err := dirAllowed(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := write(); err != nil || !was {
+		return err
+	}
+
+	allowed, err := readAllowList()
+	if err != nil {
+		return err
+	}
+	if allowed[dir], err = allowedDigest(dir); err != nil {
+		return err
+	}
+
+	return writeAllowList(allowed)
+}
+
+// checkAllowed returns an error, naming the config setting about to be
+// acted on, when the config in use is the local directory's and it has
+// not been allowed as it is now. Configs given with --config or kept in
+// the home directory are the user's own and always trusted.
+func checkAllowed(setting string) error {
+
+	used := viper.ConfigFileUsed()
+	if used == "" || flags.cfgFile != "" {
+		return nil
+	}
+
+	dir, err := realPath(filepath.Dir(used))
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if wd, err = realPath(wd); err != nil {
+		return err
+	}
+	if dir != wd {
+		return nil
+	}
+
+	allowed, err := readAllowList()
+	if err != nil {
+		return err
+	}
+
+	d, err := configDigest(dir)
+	if err != nil {
+		return err
+	}
+
+	// the dotenv files do not matter to the settings of the config
+	switch strings.SplitN(allowed[dir], " ", 2)[0] {
+	case d:
+		return nil
+	case "":
+		return fmt.Errorf("%s in %s has not been allowed, review the file and run loadenv allow", setting, filepath.Base(used))
+	default:
+		return fmt.Errorf("%s changed since it was allowed, review it and run loadenv allow to use its %s", filepath.Base(used), setting)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// testProject makes a temporary home directory and a project directory
+// the test runs in, and returns the project directory.
+func testProject(t *testing.T) string {
+
+	homedir.DisableCache = true
+	t.Setenv("HOME", t.TempDir())
+
+	dir, err := realPath(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	flags = newFlagValues()
+	flags.quiet = true
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	return dir
+}
+
+func TestCheckAllowedNonYAMLConfig(t *testing.T) {
+
+	dir := testProject(t)
+
+	if err := setAllowed([]string{dir}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// viper picks .loadenv.json before .loadenv.yaml
+	fname := filepath.Join(dir, ".loadenv.json")
+	if err := os.WriteFile(fname, []byte(`{"compose": {"path": "/tmp/evil"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(fname)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := dirAllowed(dir); err != nil || ok {
+		t.Errorf("dirAllowed after adding %s = %v, %v, want false", fname, ok, err)
+	}
+	if err := checkAllowed("compose.path"); err == nil {
+		t.Errorf("checkAllowed after adding %s succeeded, want an error", fname)
+	}
+
+	if err := setAllowed([]string{dir}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkAllowed("compose.path"); err != nil {
+		t.Errorf("checkAllowed after allowing %s: %v", fname, err)
+	}
+}
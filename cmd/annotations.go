@@ -0,0 +1,148 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+)
+
+// annotations reads the "# @tag value" comments directly above each key
+// of a dotenv file, returning the values by key and tag.
+func annotations(fname string) (map[string]map[string]string, error) {
+
+	d, err := selectedDialect()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// logical lines, so the lines of a multi-line value are not taken
+	// for comments
+	lines, err := dotenv.ReadLines(f, fname, d)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[string]map[string]string)
+	pending := make(map[string]string)
+
+	for _, l := range lines {
+		if l.Var == nil && isAnnotation(l.Text) {
+			comment := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l.Text), "#"))
+			kv := strings.SplitN(comment[1:], " ", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "")
+			}
+			pending[kv[0]] = strings.TrimSpace(kv[1])
+			continue
+		}
+		if l.Var == nil && strings.HasPrefix(strings.TrimSpace(l.Text), "#") {
+			continue
+		}
+
+		// a blank line detaches the comments from the next key
+		if l.Var != nil && len(pending) > 0 {
+			all[l.Var.Key] = pending
+		}
+		pending = make(map[string]string)
+	}
+
+	return all, nil
+}
+
+// keyDescriptions returns the descriptions of the keys by key: the
+// "# @desc" annotations of the dotenv file, or else the descriptions in s,
+// which may be nil.
+func keyDescriptions(s *schema) (map[string]string, error) {
+
+	descs := make(map[string]string)
+	if s != nil {
+		for key, k := range s.Keys {
+			if k.Description != "" {
+				descs[key] = k.Description
+			}
+		}
+	}
+
+	notes, err := annotations(dotenvFileName())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for key, tags := range notes {
+		if tags["desc"] != "" {
+			descs[key] = tags["desc"]
+		}
+	}
+
+	return descs, nil
+}
+
+// isAnnotation reports whether line is a "# @tag value" comment.
+func isAnnotation(line string) bool {
+
+	line = strings.TrimSpace(line)
+
+	return strings.HasPrefix(line, "#") && strings.HasPrefix(strings.TrimSpace(line[1:]), "@")
+}
+
+// warnDeprecated prints a warning for every key of vars annotated with
+// "# @deprecated use NEW_KEY" in fname.
+func warnDeprecated(fname string, vars []envVar) error {
+
+	deprecated, err := deprecations(fname, vars)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deprecated {
+		warn("%s\n", d)
+	}
+
+	return nil
+}
+
+// deprecations describes every key of vars annotated with "# @deprecated"
+// in fname.
+func deprecations(fname string, vars []envVar) ([]string, error) {
+
+	notes, err := annotations(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var deprecated []string
+	for _, v := range vars {
+		reason, ok := notes[v.Key]["deprecated"]
+		if !ok {
+			continue
+		}
+
+		data := map[string]interface{}{"Key": v.Key, "Reason": reason}
+		if reason == "" {
+			deprecated = append(deprecated, tr(message{ID: "KeyDeprecated", Other: "{{.Key}} is deprecated"}, data))
+		} else {
+			deprecated = append(deprecated, tr(message{ID: "KeyDeprecatedReason", Other: "{{.Key}} is deprecated, {{.Reason}}"}, data))
+		}
+	}
+
+	return deprecated, nil
+}
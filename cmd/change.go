@@ -0,0 +1,204 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("changes.dir", ".loadenv/changes")
+}
+
+// diffContext is how many unchanged lines surround a change in a hunk.
+const diffContext = 3
+
+// protectedKeys returns the keys of keys the schema marks as protected.
+func protectedKeys(keys []string) ([]string, error) {
+
+	s, err := readSchema()
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	var protected []string
+	for _, key := range keys {
+		if s.Keys[key].Protected {
+			protected = append(protected, key)
+		}
+	}
+
+	return protected, nil
+}
+
+// writeChange writes the change from the before to the after lines of
+// fname, which touches the protected keys, to a patch in changes.dir for
+// review instead of changing fname.
+func writeChange(fname string, before, after []string, keys []string) error {
+
+	patch := unifiedDiff(fname, before, after)
+	if patch == "" {
+		info("%s already has these values\n", fname)
+		return nil
+	}
+
+	dir := viper.GetString("changes.dir")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	name := time.Now().Format("20060102-150405") + "-" + strings.ToLower(strings.Join(keys, "-")) + ".patch"
+	path := filepath.Join(dir, name)
+
+	// the patch holds the proposed values, so it is as private as the file
+	if err := os.WriteFile(path, []byte(patch), 0600); err != nil {
+		return err
+	}
+
+	verb := "are"
+	if len(keys) == 1 {
+		verb = "is"
+	}
+	info("%s %s protected, wrote the change to %s for review instead\n", strings.Join(keys, ", "), verb, path)
+	info("Once approved, apply it with git apply %s\n", path)
+
+	return nil
+}
+
+// unifiedDiff returns the changes from before to after as a unified diff
+// of fname that git apply and patch -p1 take, or "" when they are equal.
+func unifiedDiff(fname string, before, after []string) string {
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// ops are the lines of the diff, each prefixed with ' ', '-' or '+'
+	var ops []string
+	changed := false
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			ops = append(ops, " "+before[i])
+			i++
+			j++
+		case i < len(before) && (j == len(after) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, "-"+before[i])
+			changed = true
+			i++
+		default:
+			ops = append(ops, "+"+after[j])
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", filepath.ToSlash(fname), filepath.ToSlash(fname))
+
+	// oldLine and newLine are the line numbers at ops[k]
+	oldLine, newLine := 1, 1
+	for k := 0; k < len(ops); {
+		if ops[k][0] == ' ' {
+			oldLine++
+			newLine++
+			k++
+			continue
+		}
+
+		// a hunk starts diffContext lines before the change and runs
+		// until diffContext unchanged lines follow the last change
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end, unchanged := k, 0
+		for ; end < len(ops) && unchanged <= 2*diffContext; end++ {
+			if ops[end][0] == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		if unchanged > diffContext {
+			end -= unchanged - diffContext
+		}
+
+		hunkOld, hunkNew := oldLine-(k-start), newLine-(k-start)
+		var oldCount, newCount int
+		var body bytes.Buffer
+		for _, op := range ops[start:end] {
+			if op[0] != '+' {
+				oldCount++
+			}
+			if op[0] != '-' {
+				newCount++
+			}
+			body.WriteString(op + "\n")
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount))
+		b.Write(body.Bytes())
+
+		for _, op := range ops[k:end] {
+			if op[0] != '+' {
+				oldLine++
+			}
+			if op[0] != '-' {
+				newLine++
+			}
+		}
+		k = end
+	}
+
+	return b.String()
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// side starts at the line before it.
+func hunkRange(start, count int) string {
+
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,167 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("chaos.pumba_image", "gaiaadm/pumba")
+	viper.SetDefault("chaos.tc_image", "gaiadocker/iproute2")
+}
+
+// NewChaosCmd returns the chaos command.
+func NewChaosCmd() *cobra.Command {
+
+	chaosCmd := &cobra.Command{
+		Use:   "chaos",
+		Short: "Inject latency into or kill services of the running stack",
+		Long: `Chaos adds network latency to services with pumba, e.g. --latency db=200ms,
+and kills services after a delay, e.g. --kill queue@5m, so retry logic can
+be tested against the local stack. Latency is removed after --duration.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := chaos(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	chaosCmd.Flags().StringSliceVar(&flags.chaosLatency, "latency", nil, "add latency to a service, as service=delay")
+	chaosCmd.Flags().StringSliceVar(&flags.chaosKill, "kill", nil, "kill a service after a delay, as service@delay")
+	chaosCmd.Flags().DurationVar(&flags.chaosDuration, "duration", 10*time.Minute, "how long latency is injected for")
+
+	return chaosCmd
+}
+
+// chaos runs the requested faults and waits until they are over.
+func chaos() error {
+
+	if len(flags.chaosLatency) == 0 && len(flags.chaosKill) == 0 {
+		return fmt.Errorf("nothing to do, give --latency or --kill")
+	}
+
+	st, err := attachState()
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return fmt.Errorf("no stack started by loadenv in the local directory")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(flags.chaosLatency)+len(flags.chaosKill))
+
+	for _, spec := range flags.chaosLatency {
+		service, delay, err := splitChaosSpec(spec, "=")
+		if err != nil {
+			return err
+		}
+
+		container, err := serviceContainer(service)
+		if err != nil {
+			return err
+		}
+
+		c := exec.Command("docker", "run", "--rm",
+			"-v", "/var/run/docker.sock:/var/run/docker.sock",
+			viper.GetString("chaos.pumba_image"),
+			"netem", "--duration", flags.chaosDuration.String(), "--tc-image", viper.GetString("chaos.tc_image"),
+			"delay", "--time", strconv.FormatInt(int64(delay/time.Millisecond), 10),
+			container)
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+
+		info("Adding %s of latency to %s for %s\n", delay, service, flags.chaosDuration)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runAttached(c); err != nil {
+				errs <- fmt.Errorf("pumba: %v", err)
+			}
+		}()
+	}
+
+	for _, spec := range flags.chaosKill {
+		service, delay, err := splitChaosSpec(spec, "@")
+		if err != nil {
+			return err
+		}
+
+		info("Killing %s in %s\n", service, delay)
+
+		wg.Add(1)
+		time.AfterFunc(delay, func() {
+			defer wg.Done()
+			info("Killing %s\n", service)
+			if err := runDocker(composeCommand("kill", service)); err != nil {
+				errs <- err
+			}
+		})
+	}
+
+	wg.Wait()
+	close(errs)
+
+	// the first error, or nil when there was none
+	return <-errs
+}
+
+// splitChaosSpec splits service<sep>duration. A bare number is taken as
+// milliseconds.
+func splitChaosSpec(spec, sep string) (string, time.Duration, error) {
+
+	kv := strings.SplitN(spec, sep, 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return "", 0, fmt.Errorf("invalid %q, use service%sduration", spec, sep)
+	}
+
+	if ms, err := strconv.Atoi(kv[1]); err == nil {
+		return kv[0], time.Duration(ms) * time.Millisecond, nil
+	}
+
+	d, err := time.ParseDuration(kv[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid duration in %q: %v", spec, err)
+	}
+
+	return kv[0], d, nil
+}
+
+// serviceContainer returns the id of the running container of service.
+func serviceContainer(service string) (string, error) {
+
+	out, err := dockerOutput(composeCommand("ps", "-q", service))
+	if err != nil {
+		return "", fmt.Errorf("can not find the container of %s: %v", service, err)
+	}
+
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return "", fmt.Errorf("service %s is not running", service)
+	}
+
+	return ids[0], nil
+}
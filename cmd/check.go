@@ -0,0 +1,133 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCheckCmd returns the check command.
+func NewCheckCmd() *cobra.Command {
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Compare the dotenv file against .env.example",
+		Long: `Check compares the dotenv file with .env.example and reports the keys
+the example has but the dotenv file is missing, the keys only the dotenv
+file has and the required keys that are empty. Keys are required when
+` + schemaFile + ` says so or the example marks them with a
+"# @required" comment.
+
+With --exit-code it exits with 1 when anything was reported, for CI.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			problems, err := checkExampleFile(os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+			if problems > 0 && flags.checkExitCode {
+				exit(1)
+			}
+		},
+	}
+
+	checkCmd.Flags().StringVar(&flags.checkExample, "example", ".env.example", "example file to compare against")
+	checkCmd.Flags().BoolVar(&flags.checkExitCode, "exit-code", false, "exit with 1 when there are differences")
+
+	return checkCmd
+}
+
+// compareExample returns the keys the example has that the dotenv file
+// is missing, the keys only the dotenv file has and the required keys the
+// dotenv file leaves empty, each sorted.
+func compareExample(example string) (missing, extra, empty []string, err error) {
+
+	local, err := readEnvMap(dotenvFileName())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	want, err := readEnvMap(example)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	required := make(map[string]bool)
+	notes, err := annotations(example)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for key, tags := range notes {
+		if _, ok := tags["required"]; ok {
+			required[key] = true
+		}
+	}
+	s, err := readSchema()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if s != nil {
+		for key, k := range s.Keys {
+			if k.Required {
+				required[key] = true
+			}
+		}
+	}
+
+	for key := range want {
+		if _, ok := local[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	for key, value := range local {
+		if _, ok := want[key]; !ok {
+			extra = append(extra, key)
+		}
+		if value == "" && required[key] {
+			empty = append(empty, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	sort.Strings(empty)
+
+	return missing, extra, empty, nil
+}
+
+// checkExampleFile writes the differences between the dotenv file and the
+// example to w and returns how many there are.
+func checkExampleFile(w io.Writer) (int, error) {
+
+	missing, extra, empty, err := compareExample(flags.checkExample)
+	if err != nil {
+		return 0, err
+	}
+
+	problems := len(missing) + len(extra) + len(empty)
+	if problems == 0 {
+		fmt.Fprintf(w, "%s matches %s\n", dotenvFileName(), flags.checkExample)
+		return 0, nil
+	}
+
+	printSection(w, "Missing from "+dotenvFileName(), "-", missing)
+	printSection(w, "Not in "+flags.checkExample, "+", extra)
+	printSection(w, "Required but empty", "!", empty)
+
+	return problems, nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCommands are the commands that write stdin to the clipboard,
+// in the order they are tried.
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// pasteCommands are the commands that print the clipboard, in the same
+// order as clipboardCommands.
+var pasteCommands = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+	{"xsel", "--clipboard", "--output"},
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+}
+
+// readClipboard returns the contents of the system clipboard.
+func readClipboard() (string, error) {
+
+	for _, args := range pasteCommands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("can not read the clipboard with %s: %v", args[0], err)
+		}
+		return string(out), nil
+	}
+
+	return "", fmt.Errorf("can not find a command to read the clipboard")
+}
+
+// copyToClipboard puts value on the system clipboard.
+func copyToClipboard(value string) error {
+
+	for _, args := range clipboardCommands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+
+		c := exec.Command(args[0], args[1:]...)
+		c.Stdin = strings.NewReader(value)
+		if out, err := c.CombinedOutput(); err != nil {
+			return fmt.Errorf("can not copy to the clipboard with %s: %s", args[0], strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("can not find a clipboard command, install xclip, xsel or wl-copy")
+}
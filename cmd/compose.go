@@ -0,0 +1,574 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// tmpDir is where loadenv keeps the files it generates for a project.
+const tmpDir = ".loadenv/tmp"
+
+var (
+	// overrideFiles holds the compose override files generated for the
+	// current invocation, in the order they should be applied.
+	overrideFiles []string
+
+	// composeProject, when set, is passed to docker-compose as the
+	// project name.
+	composeProject string
+)
+
+// composeFile is the subset of a docker-compose file loadenv understands.
+type composeFile struct {
+	Version  string                     `yaml:"version,omitempty"`
+	Services map[string]composeService  `yaml:"services"`
+	Networks map[string]composeResource `yaml:"networks,omitempty"`
+	Volumes  map[string]composeResource `yaml:"volumes,omitempty"`
+}
+
+// composeService is the subset of a compose service loadenv understands.
+type composeService struct {
+	Image          string        `yaml:"image,omitempty"`
+	Build          interface{}   `yaml:"build,omitempty"`
+	CPUs           interface{}   `yaml:"cpus,omitempty"`
+	MemLimit       interface{}   `yaml:"mem_limit,omitempty"`
+	MemReservation interface{}   `yaml:"mem_reservation,omitempty"`
+	Deploy         composeDeploy `yaml:"deploy,omitempty"`
+	EnvFile        interface{}   `yaml:"env_file,omitempty"`
+}
+
+// composeDeploy is the deploy section of a service, where version 3
+// compose files declare resources.
+type composeDeploy struct {
+	Resources struct {
+		Limits       composeLimits `yaml:"limits,omitempty"`
+		Reservations composeLimits `yaml:"reservations,omitempty"`
+	} `yaml:"resources,omitempty"`
+}
+
+// composeLimits are the cpus and memory of a deploy resources section.
+type composeLimits struct {
+	CPUs   interface{} `yaml:"cpus,omitempty"`
+	Memory interface{} `yaml:"memory,omitempty"`
+}
+
+// composeResource is a top-level network or volume of a compose file.
+type composeResource struct {
+	Name     string      `yaml:"name,omitempty"`
+	External interface{} `yaml:"external,omitempty"`
+}
+
+// externalName returns the docker name of an external resource declared
+// under key, and whether the resource is external at all. Both the
+// `external: true` and the older `external: {name: ...}` forms are handled.
+func (r composeResource) externalName(key string) (string, bool) {
+
+	switch ext := r.External.(type) {
+	case bool:
+		if !ext {
+			return "", false
+		}
+	case map[interface{}]interface{}:
+		if name, ok := ext["name"].(string); ok {
+			return name, true
+		}
+	default:
+		return "", false
+	}
+
+	if r.Name != "" {
+		return r.Name, true
+	}
+
+	return key, true
+}
+
+// composeFileNames are the compose files compose looks for in the project
+// directory, in its order of preference, and composeOverrideNames the
+// override files it reads after the one found.
+var (
+	composeFileNames     = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+	composeOverrideNames = []string{"compose.override.yml", "compose.override.yaml", "docker-compose.override.yml", "docker-compose.override.yaml"}
+)
+
+// findComposeFile returns the first compose file of the project.
+func findComposeFile() (string, error) {
+
+	if files := composeFiles(); len(files) > 0 {
+		return files[0], nil
+	}
+
+	return "", fmt.Errorf("can not find compose.yaml or docker-compose.yml file in the local directory")
+}
+
+// buildsImages reports whether a service of the compose files has a build
+// section, or whether it can not be told as there are no readable compose
+// files.
+func buildsImages() bool {
+
+	dockerfiles, ok := serviceDockerfiles()
+
+	return !ok || len(dockerfiles) > 0
+}
+
+// serviceDockerfiles returns the Dockerfile of each service of the compose
+// files with a build section, resolved against its build context, or ""
+// when it can not be checked, e.g. for a git context or an inline
+// Dockerfile. Later compose files override the build of earlier ones. ok
+// is false when there are no readable compose files.
+func serviceDockerfiles() (dockerfiles map[string]string, ok bool) {
+
+	files := composeFiles()
+	if len(files) == 0 {
+		return nil, false
+	}
+
+	dockerfiles = make(map[string]string)
+	for _, fname := range files {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			return nil, false
+		}
+
+		for name, s := range c.Services {
+			context, dockerfile := ".", "Dockerfile"
+			switch b := s.Build.(type) {
+			case nil:
+				continue
+			case string:
+				context = b
+			case map[interface{}]interface{}:
+				if v, ok := b["context"].(string); ok {
+					context = v
+				}
+				if v, ok := b["dockerfile"].(string); ok {
+					dockerfile = v
+				}
+				if _, inline := b["dockerfile_inline"]; inline {
+					dockerfiles[name] = ""
+					continue
+				}
+			}
+
+			if strings.Contains(context, "://") || strings.HasPrefix(context, "git@") {
+				dockerfiles[name] = ""
+				continue
+			}
+			if !filepath.IsAbs(context) {
+				context = filepath.Join(filepath.Dir(fname), context)
+			}
+			if !filepath.IsAbs(dockerfile) {
+				dockerfile = filepath.Join(context, dockerfile)
+			}
+			dockerfiles[name] = dockerfile
+		}
+	}
+
+	return dockerfiles, true
+}
+
+// missingDockerfiles returns the Dockerfiles the compose files build from
+// that do not exist, along with their services, or the Dockerfile of the
+// local directory when the compose files can not be read.
+func missingDockerfiles() []string {
+
+	dockerfiles, ok := serviceDockerfiles()
+	if !ok {
+		dockerfiles = map[string]string{"": "Dockerfile"}
+	}
+
+	services := make(map[string][]string)
+	for name, dockerfile := range dockerfiles {
+		if dockerfile == "" {
+			continue
+		}
+		if _, err := os.Stat(dockerfile); os.IsNotExist(err) {
+			services[dockerfile] = append(services[dockerfile], name)
+		}
+	}
+
+	var missing []string
+	for dockerfile, names := range services {
+		sort.Strings(names)
+		if names[0] == "" {
+			missing = append(missing, dockerfile)
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%s (%s)", dockerfile, strings.Join(names, ", ")))
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// composeFiles returns the compose files compose reads by default: those
+// listed in COMPOSE_FILE, or the first of composeFileNames in the current
+// directory followed by the first of composeOverrideNames.
+func composeFiles() []string {
+
+	var files []string
+
+	if list := os.Getenv("COMPOSE_FILE"); list != "" {
+		sep := os.Getenv("COMPOSE_PATH_SEPARATOR")
+		if sep == "" {
+			sep = string(os.PathListSeparator)
+		}
+		for _, fname := range strings.Split(list, sep) {
+			if fname != "" {
+				files = append(files, fname)
+			}
+		}
+		return files
+	}
+
+	for _, names := range [][]string{composeFileNames, composeOverrideNames} {
+		for _, fname := range names {
+			if _, err := os.Stat(fname); err == nil {
+				files = append(files, fname)
+				break
+			}
+		}
+		// an override is only read along with a compose file
+		if len(files) == 0 {
+			break
+		}
+	}
+
+	return files
+}
+
+// stackImages returns the image each service of the stack runs. Services
+// that are built use the image name docker-compose gives them.
+func stackImages() map[string]string {
+
+	images := make(map[string]string)
+	for _, fname := range append(composeFiles(), overrideFiles...) {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			continue
+		}
+		for name, svc := range c.Services {
+			switch {
+			case svc.Image != "":
+				images[name] = svc.Image
+			case svc.Build != nil && images[name] == "":
+				images[name] = builtImageName(name)
+			}
+		}
+	}
+
+	return images
+}
+
+// builtImageName returns the name compose gives the image it builds for
+// service: the plugin joins project and service with a dash, the
+// standalone docker-compose with an underscore.
+func builtImageName(service string) string {
+
+	if len(composeBinary()) > 1 {
+		return stackProject() + "-" + service
+	}
+
+	return stackProject() + "_" + service
+}
+
+// readComposeFile parses the given compose file.
+func readComposeFile(fname string) (*composeFile, error) {
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var c composeFile
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+
+	return &c, nil
+}
+
+// writeOverride marshals override into a compose override file named name
+// inside the tmp dir and registers it to be passed to docker-compose. The
+// version of the project's compose file is used unless override sets one.
+func writeOverride(name string, override map[string]interface{}) error {
+
+	if _, ok := override["version"]; !ok {
+		if fname, err := findComposeFile(); err == nil {
+			if c, err := readComposeFile(fname); err == nil && c.Version != "" {
+				override["version"] = c.Version
+			}
+		}
+	}
+
+	b, err := yaml.Marshal(override)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return err
+	}
+
+	fname := filepath.Join(tmpDir, name)
+	if err := os.WriteFile(fname, b, 0644); err != nil {
+		return err
+	}
+
+	overrideFiles = append(overrideFiles, fname)
+
+	return nil
+}
+
+// reapplyOverride rewrites the override name of the running stack, if
+// any, with setup and recreates services so they pick it up.
+func reapplyOverride(name string, setup func() error, services []string) error {
+
+	st, err := attachState()
+	if err != nil || st == nil {
+		return err
+	}
+
+	fname := filepath.Join(tmpDir, name)
+	var kept []string
+	for _, o := range overrideFiles {
+		if o != fname {
+			kept = append(kept, o)
+		}
+	}
+	overrideFiles = kept
+	os.Remove(fname)
+
+	if err := setup(); err != nil {
+		return err
+	}
+
+	st.Overrides = overrideFiles
+	if !hasString(st.TempFiles, fname) {
+		st.TempFiles = append(st.TempFiles, fname)
+	}
+	if err := writeState(st); err != nil {
+		return err
+	}
+
+	// compose interpolates the project files with the loaded environment
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	args := append([]string{"up", "-d", "--no-deps"}, services...)
+
+	return runDocker(composeCommand(args...))
+}
+
+// detectedCompose caches the compose implementation found on the host.
+var detectedCompose []string
+
+// composePathWarned is set once the warning about an unallowed
+// compose.path has been printed.
+var composePathWarned bool
+
+// checkComposeChoice returns an error when --compose or the
+// compose.command config names an unknown implementation.
+func checkComposeChoice() error {
+
+	choice := flags.composeChoice
+	if choice == "" {
+		choice = viper.GetString("compose.command")
+	}
+
+	switch choice {
+	case "", "auto", "plugin", "standalone":
+		return nil
+	}
+
+	return fmt.Errorf("unknown compose implementation %q, use auto, plugin or standalone", choice)
+}
+
+// composeBinary returns the command, with its leading arguments, that
+// runs compose: the docker compose plugin when it is installed and the
+// standalone docker-compose otherwise, unless one is forced with
+// --compose or the compose.command config. The compose.path config
+// replaces the executable, a docker binary for the plugin or any other
+// executable for standalone compose. Nearly every command runs it, so a
+// compose.path from the local project config is only used once allowed.
+func composeBinary() []string {
+
+	choice := flags.composeChoice
+	if choice == "" {
+		choice = viper.GetString("compose.command")
+	}
+
+	path := viper.GetString("compose.path")
+	if path != "" {
+		if err := checkAllowed("compose.path"); err != nil {
+			if !composePathWarned {
+				warn("%v, using the default compose instead\n", err)
+				composePathWarned = true
+			}
+			path = ""
+		}
+	}
+
+	if path != "" {
+		if choice == "plugin" || (choice != "standalone" && filepath.Base(path) == "docker") {
+			return []string{path, "compose"}
+		}
+		return []string{path}
+	}
+
+	switch choice {
+	case "plugin":
+		return []string{"docker", "compose"}
+	case "standalone":
+		return []string{"docker-compose"}
+	}
+
+	if detectedCompose == nil {
+		detectedCompose = []string{"docker-compose"}
+		if runDocker(exec.Command("docker", "compose", "version")) == nil {
+			detectedCompose = []string{"docker", "compose"}
+		}
+	}
+
+	return detectedCompose
+}
+
+// composeVersion returns the version of the compose implementation in use.
+func composeVersion() string {
+
+	bin := composeBinary()
+
+	return commandVersion(bin[0], append(bin[1:], "version", "--short")...)
+}
+
+// composeCommand returns a docker-compose command for the given arguments.
+// When override files have been generated the base compose files are
+// listed explicitly so the overrides are layered on top of them, unless
+// none is found, leaving compose to look for them on its own. The
+// variables come from composeEnvFile once it has been written.
+func composeCommand(args ...string) *exec.Cmd {
+
+	var fargs []string
+
+	if composeProject != "" {
+		fargs = append(fargs, "-p", composeProject)
+	}
+
+	if files := composeFiles(); len(overrideFiles) > 0 && len(files) > 0 {
+		for _, fname := range append(files, overrideFiles...) {
+			fargs = append(fargs, "-f", fname)
+		}
+	}
+
+	var env []string
+	if _, err := os.Stat(composeEnvFile); err == nil && !inheritsEnv() {
+		if env, err = composeEnv(); err == nil {
+			fargs = append(fargs, "--env-file", composeEnvFile)
+		}
+	}
+
+	fargs = append(fargs, flags.composeArgs...)
+
+	bin := composeBinary()
+	c := exec.Command(bin[0], append(append(bin[1:], fargs...), args...)...)
+	c.Env = env
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return c
+}
+
+// varRefRe matches variable references in compose/dotenv syntax. An
+// escaped $$ is matched too so it can be skipped.
+var varRefRe = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)[^}]*\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// varRefs returns the names of the variables referenced in s.
+func varRefs(s string) []string {
+
+	var refs []string
+
+	for _, m := range varRefRe.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			refs = append(refs, m[1])
+		} else if m[2] != "" {
+			refs = append(refs, m[2])
+		}
+	}
+
+	return refs
+}
+
+// serviceVarRefs parses the given compose file and returns, for every
+// service, the sorted names of the variables its definition references.
+func serviceVarRefs(fname string) (map[string][]string, error) {
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Services map[string]interface{} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+
+	refs := make(map[string][]string)
+	for name, svc := range raw.Services {
+		seen := make(map[string]bool)
+		walkStrings(svc, func(s string) {
+			for _, ref := range varRefs(s) {
+				seen[ref] = true
+			}
+		})
+
+		refs[name] = []string{}
+		for ref := range seen {
+			refs[name] = append(refs[name], ref)
+		}
+		sort.Strings(refs[name])
+	}
+
+	return refs, nil
+}
+
+// walkStrings calls fn for every string, key or value, found in v.
+func walkStrings(v interface{}, fn func(string)) {
+
+	switch v := v.(type) {
+	case string:
+		fn(v)
+	case []interface{}:
+		for _, e := range v {
+			walkStrings(e, fn)
+		}
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			walkStrings(k, fn)
+			walkStrings(e, fn)
+		}
+	}
+}
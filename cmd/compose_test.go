@@ -0,0 +1,49 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestComposeFiles(t *testing.T) {
+
+	testProject(t)
+	t.Setenv("COMPOSE_FILE", "")
+
+	compose := "services:\n  app:\n    build: ./app\n  db:\n    image: mysql\n"
+	if err := os.WriteFile("compose.yaml", []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("docker-compose.override.yaml", []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"compose.yaml", "docker-compose.override.yaml"}
+	if got := composeFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("composeFiles() = %q, want %q", got, want)
+	}
+
+	// only the app service builds, from a context without a Dockerfile
+	if got, want := missingDockerfiles(), []string{"app/Dockerfile (app)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("missingDockerfiles() = %q, want %q", got, want)
+	}
+
+	t.Setenv("COMPOSE_FILE", "compose.yaml"+string(os.PathListSeparator)+"ci.yaml")
+	if got, want := composeFiles(), []string{"compose.yaml", "ci.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("composeFiles() with COMPOSE_FILE = %q, want %q", got, want)
+	}
+}
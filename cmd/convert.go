@@ -0,0 +1,97 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/cobra"
+)
+
+// NewConvertCmd returns the convert command.
+func NewConvertCmd() *cobra.Command {
+
+	convertCmd := &cobra.Command{
+		Use:   "convert <file>",
+		Short: "Convert a dotenv file from one dialect to another",
+		Long: `Convert rewrites the quoting, escaping and interpolation of a dotenv file
+from one dialect's semantics to another's, preserving every value exactly.
+It fails when a value can not be represented in the target dialect.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := convert(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	convertCmd.Flags().StringVar(&flags.convertFrom, "from", "", "dialect of the input file (posix|docker|ruby|node)")
+	convertCmd.Flags().StringVar(&flags.convertTo, "to", "", "dialect to write (posix|docker|ruby|node)")
+	convertCmd.Flags().StringVarP(&flags.convertOutput, "output", "o", "", "file to write to (default is stdout)")
+
+	return convertCmd
+}
+
+// convert converts fname and writes the result.
+func convert(fname string) error {
+
+	if flags.convertFrom == "" || flags.convertTo == "" {
+		return fmt.Errorf("both --from and --to must be given")
+	}
+
+	from, err := dotenv.LookupDialect(flags.convertFrom)
+	if err != nil {
+		return err
+	}
+
+	to, err := dotenv.LookupDialect(flags.convertTo)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	vars, err := parseEnvDialect(f, fname, from)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	for _, v := range vars {
+		line, err := to.Format(v, from.Interpolates())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(&out, line)
+	}
+
+	if flags.convertOutput == "" {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+
+	if err := checkReadOnly("convert -o"); err != nil {
+		return err
+	}
+
+	return os.WriteFile(flags.convertOutput, out.Bytes(), 0600)
+}
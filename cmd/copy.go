@@ -0,0 +1,130 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCopyCmd returns the copy command.
+func NewCopyCmd() *cobra.Command {
+
+	copyCmd := &cobra.Command{
+		Use:   "copy <key>",
+		Short: "Copy a resolved value to the clipboard without printing it",
+		Long: `Copy resolves the environment like run does and puts the value of key on
+the system clipboard, so secrets can be pasted into other tools without
+showing up in the terminal or its scrollback.
+
+The clipboard is cleared after --clear-after, unless something else was
+copied in the meantime.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			if flags.copyClear != "" {
+				err = clearClipboard(flags.copyClear)
+			} else {
+				err = copyValue(args[0])
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	copyCmd.Flags().DurationVar(&flags.copyClearAfter, "clear-after", 45*time.Second, "clear the clipboard after this long, 0 to keep the value")
+	// --clear is how copy runs itself in the background to clear the
+	// clipboard, the value is passed by digest only
+	copyCmd.Flags().StringVar(&flags.copyClear, "clear", "", "")
+	copyCmd.Flags().MarkHidden("clear")
+
+	return copyCmd
+}
+
+// copyValue copies the resolved value of key and schedules clearing it.
+func copyValue(key string) error {
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := applySchema(); err != nil {
+		return err
+	}
+
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	if err := copyToClipboard(value); err != nil {
+		return err
+	}
+
+	if flags.copyClearAfter <= 0 {
+		info("Copied %s to the clipboard\n", key)
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	clearCmd := exec.Command(self, "copy", key, "--clear", digest(value), "--clear-after", flags.copyClearAfter.String())
+	if err := clearCmd.Start(); err != nil {
+		return fmt.Errorf("can not schedule clearing the clipboard: %v", err)
+	}
+
+	info("Copied %s to the clipboard, it will be cleared in %s\n", key, flags.copyClearAfter)
+
+	return nil
+}
+
+// clearClipboard waits --clear-after and clears the clipboard if it still
+// holds the value with the given digest.
+func clearClipboard(want string) error {
+
+	// keep going when the terminal that ran copy is closed
+	signal.Ignore(syscall.SIGHUP)
+
+	time.Sleep(flags.copyClearAfter)
+
+	current, err := readClipboard()
+	if err == nil && digest(current) != want && digest(strings.TrimRight(current, "\r\n")) != want {
+		return nil
+	}
+
+	// clear anyway when the clipboard can not be read, to be safe
+	return copyToClipboard("")
+}
+
+// digest returns the hex encoded SHA-256 of value.
+func digest(value string) string {
+
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,210 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// debugFile keeps the services in debug mode between invocations.
+	debugFile = ".loadenv/debug.json"
+	// debugOverride is the override file the debug variables are set in.
+	debugOverride = "docker-compose.debug.yml"
+)
+
+func init() {
+	viper.SetDefault("debug.env", map[string]string{
+		"APP_DEBUG":   "true",
+		"LOG_LEVEL":   "debug",
+		"LOG_CHANNEL": "stderr",
+	})
+}
+
+// NewDebugCmd returns the debug command and its subcommands.
+func NewDebugCmd() *cobra.Command {
+
+	debugCmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Switch services to debug logging without editing the dotenv file",
+		Long: `Debug sets the variables of debug.env, by default APP_DEBUG=true,
+LOG_LEVEL=debug and LOG_CHANNEL=stderr, for services through a generated
+override and recreates them, so the committed dotenv file is left alone.
+Services stay in debug mode, also across restarts of the stack, until
+debug off is run.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			services, err := readDebug()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+			if len(services) == 0 {
+				info("No services are in debug mode\n")
+				return
+			}
+			fmt.Println(strings.Join(services, "\n"))
+		},
+	}
+
+	onCmd := &cobra.Command{
+		Use:   "on [service...]",
+		Short: "Switch services to debug mode (default is the app service)",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := setDebug(args, true); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	offCmd := &cobra.Command{
+		Use:   "off [service...]",
+		Short: "Switch services back from debug mode (default is all of them)",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := setDebug(args, false); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	debugCmd.AddCommand(onCmd, offCmd)
+
+	return debugCmd
+}
+
+// readDebug returns the sorted services in debug mode.
+func readDebug() ([]string, error) {
+
+	b, err := os.ReadFile(debugFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var services []string
+	if err := json.Unmarshal(b, &services); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", debugFile, err)
+	}
+
+	return services, nil
+}
+
+// setDebug switches services in or out of debug mode and recreates them
+// in the running stack.
+func setDebug(services []string, on bool) error {
+
+	action := "debug off"
+	if on {
+		action = "debug on"
+	}
+	if err := checkReadOnly(action); err != nil {
+		return err
+	}
+
+	current, err := readDebug()
+	if err != nil {
+		return err
+	}
+
+	if len(services) == 0 {
+		if on {
+			services = []string{viper.GetString("app_service")}
+		} else {
+			services = current
+		}
+	}
+
+	enabled := make(map[string]bool)
+	for _, name := range current {
+		enabled[name] = true
+	}
+	for _, name := range services {
+		if !on && !enabled[name] {
+			return fmt.Errorf("%s is not in debug mode", name)
+		}
+		enabled[name] = on
+	}
+
+	var names []string
+	for name, ok := range enabled {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		if err := os.Remove(debugFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		b, err := json.Marshal(names)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(debugFile), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(debugFile, b, 0644); err != nil {
+			return err
+		}
+	}
+
+	if len(services) == 0 {
+		return nil
+	}
+
+	if on {
+		info("Switching %s to debug mode\n", strings.Join(services, ", "))
+	} else {
+		info("Switching %s back from debug mode\n", strings.Join(services, ", "))
+	}
+
+	return reapplyOverride(debugOverride, setupDebug, services)
+}
+
+// setupDebug writes the override setting the debug variables, if any
+// service is in debug mode. Viper lower cases config keys, so the
+// variable names are upper cased.
+func setupDebug() error {
+
+	services, err := readDebug()
+	if err != nil || len(services) == 0 {
+		return err
+	}
+
+	env := make(map[string]string)
+	for key, value := range viper.GetStringMapString("debug.env") {
+		env[strings.ToUpper(key)] = value
+	}
+
+	override := make(map[string]interface{})
+	for _, name := range services {
+		override[name] = map[string]interface{}{"environment": env}
+	}
+
+	return writeOverride(debugOverride, map[string]interface{}{
+		"services": override,
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const devcontainerFile = ".devcontainer/devcontainer.json"
+
+func init() {
+	viper.SetDefault("devcontainer.workspace_folder", "/var/www/html")
+}
+
+// NewDevcontainerCmd returns the devcontainer command and its subcommands.
+func NewDevcontainerCmd() *cobra.Command {
+
+	devcontainerCmd := &cobra.Command{
+		Use:   "devcontainer",
+		Short: "Manage the VS Code devcontainer configuration",
+	}
+
+	devcontainerCmd.AddCommand(newDevcontainerGenerateCmd())
+
+	return devcontainerCmd
+}
+
+// newDevcontainerGenerateCmd returns the devcontainer generate command.
+func newDevcontainerGenerateCmd() *cobra.Command {
+
+	devcontainerGenerateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate .devcontainer/devcontainer.json from the project",
+		Long: `Generate writes .devcontainer/devcontainer.json referencing the compose
+file and the app service. Non-secret variables are inlined, secrets are
+read from the local environment when the container starts.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := generateDevcontainer(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return devcontainerGenerateCmd
+}
+
+// devcontainer is the subset of devcontainer.json loadenv generates.
+type devcontainer struct {
+	Name              string            `json:"name"`
+	DockerComposeFile []string          `json:"dockerComposeFile"`
+	Service           string            `json:"service"`
+	WorkspaceFolder   string            `json:"workspaceFolder"`
+	RemoteEnv         map[string]string `json:"remoteEnv,omitempty"`
+}
+
+// generateDevcontainer writes the devcontainer.json for the project.
+func generateDevcontainer() error {
+
+	if err := checkReadOnly("devcontainer generate"); err != nil {
+		return err
+	}
+
+	files := composeFiles()
+	if len(files) == 0 {
+		return fmt.Errorf("can not find docker-compose.yml file in the local directory")
+	}
+
+	vars, err := parseEnvLayers(dotenvFileName())
+	if err != nil {
+		return err
+	}
+
+	dc := devcontainer{
+		Name:            projectName(),
+		Service:         viper.GetString("app_service"),
+		WorkspaceFolder: viper.GetString("devcontainer.workspace_folder"),
+		RemoteEnv:       make(map[string]string),
+	}
+
+	// paths in devcontainer.json are relative to the .devcontainer directory
+	for _, fname := range files {
+		dc.DockerComposeFile = append(dc.DockerComposeFile, filepath.Join("..", fname))
+	}
+
+	for _, v := range vars {
+		if isSecret(v.Key) {
+			// resolved by VS Code from the host when the container starts
+			dc.RemoteEnv[v.Key] = "${localEnv:" + v.Key + "}"
+		} else {
+			dc.RemoteEnv[v.Key] = v.Value
+		}
+	}
+
+	b, err := json.MarshalIndent(dc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(devcontainerFile), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(devcontainerFile, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+
+	info("Wrote %s\n", devcontainerFile)
+
+	return nil
+}
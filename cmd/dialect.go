@@ -0,0 +1,34 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/viper"
+)
+
+// selectedDialect returns the dialect chosen with --dialect or the
+// dialect config key, or nil when none was chosen.
+func selectedDialect() (*dotenv.Dialect, error) {
+
+	name := flags.dialectName
+	if name == "" {
+		name = viper.GetString("dialect")
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	return dotenv.LookupDialect(name)
+}
@@ -0,0 +1,141 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// NewDiffCmd returns the diff command.
+func NewDiffCmd() *cobra.Command {
+
+	diffCmd := &cobra.Command{
+		Use:   "diff <file1> <file2>",
+		Short: "Show the keys added, removed and changed between two env files",
+		Long: `Diff compares two env files, e.g. loadenv diff .env .env.production, and
+lists the keys only the second file has, the keys only the first has and
+the keys whose values differ. Values are left out unless --show-values is
+given, so the output is safe to paste.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := diff(os.Stdout, args[0], args[1]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	diffCmd.Flags().BoolVar(&flags.diffShowValues, "show-values", false, "show the values of the keys")
+
+	return diffCmd
+}
+
+// readEnvMap reads the variables of fname as written, without expanding
+// references.
+func readEnvMap(fname string) (map[string]string, error) {
+
+	d, err := selectedDialect()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars, err := parseEnvDialect(f, fname, d)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, v := range vars {
+		env[v.Key] = v.Value
+	}
+
+	return env, nil
+}
+
+// diff writes the differences between the env files fname1 and fname2 to w.
+func diff(w io.Writer, fname1, fname2 string) error {
+
+	before, err := readEnvMap(fname1)
+	if err != nil {
+		return err
+	}
+
+	after, err := readEnvMap(fname2)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var added, removed, changed []string
+	for _, key := range keys {
+		old, inBefore := before[key]
+		value, inAfter := after[key]
+
+		switch {
+		case !inBefore:
+			added = append(added, diffLine(key, value))
+		case !inAfter:
+			removed = append(removed, diffLine(key, old))
+		case old != value:
+			if flags.diffShowValues {
+				changed = append(changed, key+": "+strconv.Quote(old)+" -> "+strconv.Quote(value))
+			} else {
+				changed = append(changed, key)
+			}
+		}
+	}
+
+	if len(added)+len(removed)+len(changed) == 0 {
+		fmt.Fprintf(w, "No differences between %s and %s\n", fname1, fname2)
+		return nil
+	}
+
+	printSection(w, "Added", "+", added)
+	printSection(w, "Removed", "-", removed)
+	printSection(w, "Changed", "~", changed)
+
+	return nil
+}
+
+// diffLine returns how a key only one of the files has is listed.
+func diffLine(key, value string) string {
+
+	if !flags.diffShowValues {
+		return key
+	}
+
+	return key + "=" + strconv.Quote(value)
+}
@@ -0,0 +1,197 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("docker.retries", 3)
+	viper.SetDefault("docker.retry_delay", "1s")
+}
+
+// unreachableDaemon are the messages of docker and compose when the daemon
+// can not be reached, which is worth another try as it may be starting.
+var unreachableDaemon = []string{
+	"Cannot connect to the Docker daemon",
+	"Is the docker daemon running",
+	"error during connect",
+	"connect: connection refused",
+	"TLS handshake timeout",
+}
+
+// runDocker runs c, a docker or compose command, within the timeout and
+// retries it when the daemon can not be reached.
+func runDocker(c *exec.Cmd) error {
+	return retryDocker(c, true, (*exec.Cmd).Run)
+}
+
+// runAttached runs c without the timeout, for commands that run as long
+// as the user wants, e.g. an attached up or exec. They run the user's own
+// commands, whose errors may read like an unreachable daemon and which
+// may not be safe to repeat, so they are never retried.
+func runAttached(c *exec.Cmd) error {
+
+	_, err := runDockerOnce(c, false, (*exec.Cmd).Run)
+
+	return err
+}
+
+// dockerOutput runs c like runDocker and returns its output.
+func dockerOutput(c *exec.Cmd) ([]byte, error) {
+
+	var out bytes.Buffer
+	c.Stdout = &out
+	err := runDocker(c)
+
+	return out.Bytes(), err
+}
+
+// retryDocker runs copies of c with run, up to docker.retries more times
+// with a doubling delay from docker.retry_delay while the daemon can not
+// be reached. c itself is only a template and is never started. Commands
+// reading stdin are run once, as a retry would not get the input again.
+func retryDocker(c *exec.Cmd, timed bool, run func(*exec.Cmd) error) error {
+
+	retries := viper.GetInt("docker.retries")
+	if c.Stdin != nil {
+		retries = 0
+	}
+
+	delay := viper.GetDuration("docker.retry_delay")
+	for attempt := 0; ; attempt++ {
+		stderr, err := runDockerOnce(c, timed, run)
+		if err == nil || attempt >= retries || !isUnreachableDaemon(stderr) {
+			return err
+		}
+
+		warn("can not reach the docker daemon, retrying in %s\n", delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// runDockerOnce runs a copy of c with run and returns what it wrote to
+// stderr, which still goes to the stderr of c as well. Its error is a
+// *dotenv.DockerError.
+func runDockerOnce(c *exec.Cmd, timed bool, run func(*exec.Cmd) error) (string, error) {
+
+	ctx := context.Background()
+	timeout := flags.dockerTimeout
+	if timeout == 0 {
+		timeout = viper.GetDuration("docker.timeout")
+	}
+	if timed && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	cc := exec.CommandContext(ctx, c.Path, c.Args[1:]...)
+	cc.Env, cc.Dir = c.Env, c.Dir
+	cc.Stdin, cc.Stdout = c.Stdin, c.Stdout
+	// children of a killed command may keep its output pipes open
+	cc.WaitDelay = time.Second
+	cc.Stderr = &stderr
+	if c.Stderr != nil {
+		cc.Stderr = io.MultiWriter(c.Stderr, &stderr)
+	}
+
+	err := run(cc)
+	if ctx.Err() == context.DeadlineExceeded {
+		return stderr.String(), &dotenv.DockerError{Command: strings.Join(c.Args, " "), Err: timeoutError{timeout}}
+	}
+	if err != nil {
+		dockerErr := &dotenv.DockerError{Command: strings.Join(c.Args, " "), Err: err}
+		if c.Stderr == nil {
+			dockerErr.Stderr = strings.TrimSpace(stderr.String())
+		}
+		return stderr.String(), dockerErr
+	}
+
+	return stderr.String(), nil
+}
+
+// timeoutError is a docker command that did not finish within timeout.
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("did not finish within %s, raise --timeout or docker.timeout if it needs longer", e.timeout)
+}
+
+func (e timeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// isUnreachableDaemon reports whether stderr says the daemon can not be
+// reached.
+func isUnreachableDaemon(stderr string) bool {
+
+	for _, msg := range unreachableDaemon {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dockerLines runs the docker cli and returns the non-empty lines of its output.
+func dockerLines(args ...string) ([]string, error) {
+
+	// the stderr is not passed on, but ends up in the error
+	out, err := dockerOutput(exec.Command("docker", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
+
+// humanSize formats a size in bytes for display.
+func humanSize(n int64) string {
+
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,39 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+)
+
+func TestRunDockerError(t *testing.T) {
+
+	testProject(t)
+
+	// the stderr of the command is not passed on, so it is in the error
+	err := runDocker(exec.Command("sh", "-c", "echo no such service >&2; exit 3"))
+
+	var dockerErr *dotenv.DockerError
+	if !errors.As(err, &dockerErr) || dockerErr.Stderr != "no such service" {
+		t.Fatalf("runDocker = %#v, want a *dotenv.DockerError with the stderr", err)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("runDocker = %v, want it to wrap an *exec.ExitError with code 3", err)
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewDoctorCmd returns the doctor command.
+func NewDoctorCmd() *cobra.Command {
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the project for common setup problems",
+		Long: `Doctor checks the project in the current directory for common setup
+problems. With --fix it applies the safe remediations automatically.
+The checks of external networks and volumes are skipped when the docker
+daemon can not be reached. A summary with the number of warnings and
+suggestions follows the report unless the summary config is false.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := doctor()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			printSummary(nil, nil)
+			if err != nil {
+				exit(1)
+			}
+		},
+	}
+
+	doctorCmd.Flags().BoolVar(&flags.doctorFix, "fix", false, "apply safe remediations automatically")
+
+	return doctorCmd
+}
+
+// check is a single doctor check. run returns a description of the
+// problem found, if any; fix, when set, remediates it and describes
+// the action taken. Both are translated, as is the name. Checks that
+// needDaemon are skipped when the daemon check found it unreachable.
+type check struct {
+	name       message
+	run        func() (string, error)
+	fix        func() (string, error)
+	daemon     bool
+	needDaemon bool
+}
+
+// checks returns the checks doctor runs, in order.
+func checks() []check {
+	return []check{
+		{name: message{ID: "DoctorCheckDotenv", Other: "dotenv file"}, run: checkDotenvExists, fix: fixDotenvExists},
+		{name: message{ID: "DoctorCheckDotenvMode", Other: "dotenv permissions"}, run: checkDotenvMode, fix: fixDotenvMode},
+		{name: message{ID: "DoctorCheckGitignore", Other: "gitignore"}, run: checkGitignore, fix: fixGitignore},
+		{name: message{ID: "DoctorCheckDockerfile", Other: "Dockerfile"}, run: checkDockerfile},
+		{name: message{ID: "DoctorCheckBaseImages", Other: "base images"}, run: checkBaseImages},
+		{name: message{ID: "DoctorCheckCompose", Other: "compose"}, run: checkComposeBinary},
+		{name: message{ID: "DoctorCheckDaemon", Other: "docker daemon"}, run: checkDockerDaemon, daemon: true},
+		{name: message{ID: "DoctorCheckWSL", Other: "WSL filesystem"}, run: checkWSLMount},
+		{name: message{ID: "DoctorCheckVM", Other: "VM resources"}, run: checkVMResources},
+		{name: message{ID: "DoctorCheckNetworks", Other: "external networks"}, run: checkNetworks, fix: fixNetworks, needDaemon: true},
+		{name: message{ID: "DoctorCheckVolumes", Other: "external volumes"}, run: checkVolumes, fix: fixVolumes, needDaemon: true},
+	}
+}
+
+// doctor runs every check, fixing problems when --fix is given, and
+// returns an error when problems remain.
+func doctor() error {
+
+	if flags.doctorFix {
+		if err := checkReadOnly("doctor --fix"); err != nil {
+			return err
+		}
+	}
+
+	var problems int
+	var actions []string
+	daemonDown := false
+
+	for _, c := range checks() {
+		name := tr(c.name, nil)
+
+		if c.needDaemon && daemonDown {
+			fmt.Printf("%-5s %s: %s\n", tr(message{ID: "DoctorSkip", Other: "skip"}, nil), name,
+				tr(message{ID: "DoctorSkipDaemon", Other: "needs the docker daemon"}, nil))
+			continue
+		}
+
+		problem, err := c.run()
+		if err != nil {
+			return err
+		}
+		if c.daemon && problem != "" {
+			daemonDown = true
+			// the other docker commands would only retry in vain
+			viper.Set("docker.retries", 0)
+		}
+
+		if problem == "" {
+			fmt.Printf("%-5s %s\n", tr(message{ID: "DoctorOK", Other: "ok"}, nil), name)
+			continue
+		}
+
+		if flags.doctorFix && c.fix != nil {
+			action, err := c.fix()
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			fmt.Printf("%-5s %s: %s\n", tr(message{ID: "DoctorFixed", Other: "fixed"}, nil), name, problem)
+			actions = append(actions, action)
+			continue
+		}
+
+		fmt.Printf("%-5s %s: %s\n", tr(message{ID: "DoctorFail", Other: "fail"}, nil), name, problem)
+		problems++
+		if c.fix != nil {
+			suggest("%s", tr(message{ID: "DoctorSuggestFix", Other: "run loadenv doctor --fix to fix the {{.Check}} check"},
+				map[string]interface{}{"Check": name}))
+		}
+	}
+
+	if len(actions) > 0 {
+		fmt.Printf("\n%s\n", tr(message{ID: "DoctorActions", Other: "Actions taken:"}, nil))
+		for _, action := range actions {
+			fmt.Printf("  - %s\n", action)
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%s", tr(message{
+			ID:    "DoctorProblems",
+			One:   "doctor found {{.Count}} problem",
+			Other: "doctor found {{.Count}} problems",
+		}, map[string]interface{}{"Count": problems}))
+	}
+
+	return nil
+}
+
+func checkDotenvExists() (string, error) {
+
+	if _, err := os.Stat(dotenvFileName()); os.IsNotExist(err) {
+		return tr(message{ID: "DoctorDotenvMissing", Other: "{{.File}} does not exist"},
+			map[string]interface{}{"File": dotenvFileName()}), nil
+	}
+
+	return "", nil
+}
+
+func fixDotenvExists() (string, error) {
+
+	src, err := os.Open(".env.example")
+	if err != nil {
+		return "", fmt.Errorf("%s", tr(message{ID: "DoctorDotenvNoExample", Other: "can not create {{.File}} without .env.example"},
+			map[string]interface{}{"File": dotenvFileName()}))
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dotenvFileName(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+
+	return tr(message{ID: "DoctorDotenvCreated", Other: "created {{.File}} from .env.example"},
+		map[string]interface{}{"File": dotenvFileName()}), nil
+}
+
+func checkDotenvMode() (string, error) {
+
+	fi, err := os.Stat(dotenvFileName())
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if fi.Mode().Perm()&0077 != 0 {
+		return tr(message{ID: "DoctorDotenvMode", Other: "{{.File}} is readable by other users ({{.Mode}})"},
+			map[string]interface{}{"File": dotenvFileName(), "Mode": fi.Mode().Perm().String()}), nil
+	}
+
+	return "", nil
+}
+
+func fixDotenvMode() (string, error) {
+
+	if err := os.Chmod(dotenvFileName(), 0600); err != nil {
+		return "", err
+	}
+
+	return tr(message{ID: "DoctorDotenvModeFixed", Other: "changed mode of {{.File}} to 0600"},
+		map[string]interface{}{"File": dotenvFileName()}), nil
+}
+
+// gitignoreEntries are the entries every project using loadenv should ignore.
+var gitignoreEntries = []string{".env", localDotenv, ".loadenv/"}
+
+// missingGitignoreEntries returns the entries absent from .gitignore.
+func missingGitignoreEntries() ([]string, error) {
+
+	b, err := os.ReadFile(".gitignore")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(b), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, entry := range gitignoreEntries {
+		if !present[entry] && !present["/"+entry] {
+			missing = append(missing, entry)
+		}
+	}
+
+	return missing, nil
+}
+
+func checkGitignore() (string, error) {
+
+	missing, err := missingGitignoreEntries()
+	if err != nil || len(missing) == 0 {
+		return "", err
+	}
+
+	return tr(message{ID: "DoctorGitignoreMissing", Other: ".gitignore is missing {{.Entries}}"},
+		map[string]interface{}{"Entries": strings.Join(missing, ", ")}), nil
+}
+
+func fixGitignore() (string, error) {
+
+	missing, err := missingGitignoreEntries()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(".gitignore")
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	f, err := os.OpenFile(".gitignore", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if len(b) > 0 && !strings.HasSuffix(string(b), "\n") {
+		fmt.Fprintln(f)
+	}
+	for _, entry := range missing {
+		fmt.Fprintln(f, entry)
+	}
+
+	return tr(message{ID: "DoctorGitignoreFixed", Other: "added {{.Entries}} to .gitignore"},
+		map[string]interface{}{"Entries": strings.Join(missing, ", ")}), nil
+}
+
+func checkDockerfile() (string, error) {
+
+	if missing := missingDockerfiles(); len(missing) > 0 {
+		return tr(message{ID: "DoctorDockerfileMissing", Other: "can not find {{.Files}}"},
+			map[string]interface{}{"Files": strings.Join(missing, ", ")}), nil
+	}
+
+	return "", nil
+}
+
+// daemonTimeout is how long doctor waits for the docker daemon to answer.
+const daemonTimeout = 10 * time.Second
+
+// checkDockerDaemon asks the daemon for its version once, without the
+// retries of runDocker, so doctor does not stall when it is down.
+func checkDockerDaemon() (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}")
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if ctx.Err() == context.DeadlineExceeded {
+			reason = tr(message{ID: "DoctorDaemonTimeout", Other: "no answer within {{.Timeout}}"},
+				map[string]interface{}{"Timeout": daemonTimeout})
+		} else if reason == "" {
+			reason = err.Error()
+		}
+		return tr(message{ID: "DoctorDaemonUnreachable", Other: "can not reach the docker daemon: {{.Reason}}"},
+			map[string]interface{}{"Reason": reason}), nil
+	}
+
+	return "", nil
+}
+
+func checkComposeBinary() (string, error) {
+
+	bin := composeBinary()
+	if err := runDocker(exec.Command(bin[0], append(bin[1:], "version")...)); err != nil {
+		if len(bin) > 1 {
+			return tr(message{ID: "DoctorComposePluginMissing", Other: "the docker compose plugin is not installed"}, nil), nil
+		}
+		return tr(message{ID: "DoctorComposeMissing", Other: "docker-compose is not installed or not in PATH"}, nil), nil
+	}
+
+	return "", nil
+}
+
+// missingExternal returns the external resources of kind ("network" or
+// "volume") declared in the compose file that do not exist yet.
+func missingExternal(kind string) ([]string, error) {
+
+	fname, err := findComposeFile()
+	if err != nil {
+		return nil, nil
+	}
+
+	c, err := readComposeFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := c.Networks
+	if kind == "volume" {
+		resources = c.Volumes
+	}
+
+	var missing []string
+	for key, r := range resources {
+		name, ok := r.externalName(key)
+		if !ok {
+			continue
+		}
+		if err := runDocker(exec.Command("docker", kind, "inspect", name)); err != nil {
+			missing = append(missing, name)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing, nil
+}
+
+// externalMessages are the problems and actions reported for external
+// networks and volumes.
+var externalMessages = map[string]struct{ missing, created, failed message }{
+	"network": {
+		missing: message{ID: "DoctorNetworksMissing", Other: "external network(s) {{.Names}} do not exist"},
+		created: message{ID: "DoctorNetworksCreated", Other: "created network(s) {{.Names}}"},
+		failed:  message{ID: "DoctorNetworkFailed", Other: "can not create network {{.Name}}: {{.Output}}"},
+	},
+	"volume": {
+		missing: message{ID: "DoctorVolumesMissing", Other: "external volume(s) {{.Names}} do not exist"},
+		created: message{ID: "DoctorVolumesCreated", Other: "created volume(s) {{.Names}}"},
+		failed:  message{ID: "DoctorVolumeFailed", Other: "can not create volume {{.Name}}: {{.Output}}"},
+	},
+}
+
+func checkExternal(kind string) (string, error) {
+
+	missing, err := missingExternal(kind)
+	if err != nil || len(missing) == 0 {
+		return "", err
+	}
+
+	return tr(externalMessages[kind].missing, map[string]interface{}{"Names": strings.Join(missing, ", ")}), nil
+}
+
+func fixExternal(kind string) (string, error) {
+
+	missing, err := missingExternal(kind)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range missing {
+		var out bytes.Buffer
+		c := exec.Command("docker", kind, "create", name)
+		c.Stdout, c.Stderr = &out, &out
+		if err := runDocker(c); err != nil {
+			return "", fmt.Errorf("%s", tr(externalMessages[kind].failed,
+				map[string]interface{}{"Name": name, "Output": strings.TrimSpace(out.String())}))
+		}
+	}
+
+	return tr(externalMessages[kind].created, map[string]interface{}{"Names": strings.Join(missing, ", ")}), nil
+}
+
+func checkNetworks() (string, error) { return checkExternal("network") }
+func fixNetworks() (string, error)   { return fixExternal("network") }
+func checkVolumes() (string, error)  { return checkExternal("volume") }
+func fixVolumes() (string, error)    { return fixExternal("volume") }
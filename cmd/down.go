@@ -0,0 +1,156 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+
+	// downPassthrough are the arguments given after -- for compose down.
+	downPassthrough []string
+)
+
+// NewDownCmd returns the down command.
+func NewDownCmd() *cobra.Command {
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Stop the stack and remove what loadenv created",
+		Long: `Down runs docker-compose down for the stack started in the local
+directory and removes the files loadenv generated for it.
+
+Afterwards --prune-images dangling removes the dangling images of all
+projects and --prune-images project the images built for this one, and
+--prune-builder-cache empties the build cache. Both default to the
+down.prune_images and down.prune_builder_cache config, which can be set
+per environment under profiles.<env>.down.
+
+Arguments after -- are passed on to compose down.`,
+		Args: passthroughArgs(&downPassthrough),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !cmd.Flags().Changed("prune-images") {
+				flags.downPruneImages = viper.GetString(profileKey("down.prune_images"))
+			}
+			if !cmd.Flags().Changed("prune-builder-cache") {
+				flags.downPruneCache = viper.GetBool(profileKey("down.prune_builder_cache"))
+			}
+
+			if err := down(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	downCmd.Flags().BoolVarP(&flags.downVolumes, "volumes", "v", false, "also remove the stack's named volumes")
+	downCmd.Flags().BoolVar(&flags.downRemoveOrphans, "remove-orphans", false, "also remove containers of services no longer in the compose file")
+	downCmd.Flags().StringVar(&flags.downPruneImages, "prune-images", "", "remove images after stopping the stack (dangling|project)")
+	downCmd.Flags().BoolVar(&flags.downPruneCache, "prune-builder-cache", false, "empty the docker build cache after stopping the stack")
+
+	return downCmd
+}
+
+// down stops the stack and prunes what was asked for.
+func down() error {
+
+	switch flags.downPruneImages {
+	case "", "dangling", "project":
+	default:
+		return fmt.Errorf("unknown --prune-images %q, use dangling or project", flags.downPruneImages)
+	}
+
+	var downArgs []string
+	if flags.downVolumes {
+		downArgs = append(downArgs, "--volumes")
+	}
+	if flags.downRemoveOrphans {
+		downArgs = append(downArgs, "--remove-orphans")
+	}
+	downArgs = append(downArgs, downPassthrough...)
+
+	// the images are known from the overrides stopDocker removes
+	if _, err := attachState(); err != nil {
+		return err
+	}
+	images := builtImages()
+
+	if err := stopDocker(downArgs...); err != nil {
+		return err
+	}
+
+	switch flags.downPruneImages {
+	case "dangling":
+		out, err := dockerLines("image", "prune", "-f")
+		if err != nil {
+			return err
+		}
+		printLast(out)
+	case "project":
+		for _, image := range images {
+			if _, err := dockerLines("image", "rm", image); err != nil {
+				// the image may never have been built
+				var dockerErr *dotenv.DockerError
+				if errors.As(err, &dockerErr) && strings.Contains(dockerErr.Stderr, "No such image") {
+					continue
+				}
+				return err
+			}
+			info("removed image %s\n", image)
+		}
+	}
+
+	if flags.downPruneCache {
+		out, err := dockerLines("builder", "prune", "-f")
+		if err != nil {
+			return err
+		}
+		printLast(out)
+	}
+
+	return nil
+}
+
+// builtImages returns the sorted names of the images compose builds for
+// the stack, leaving out pulled images other projects may share.
+func builtImages() []string {
+
+	var images []string
+	for service, image := range stackImages() {
+		if image == builtImageName(service) {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+
+	return images
+}
+
+// printLast prints the last line of docker output, the summary of what a
+// prune reclaimed.
+func printLast(lines []string) {
+
+	if len(lines) > 0 {
+		info("%s\n", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewDuCmd returns the du command.
+func NewDuCmd() *cobra.Command {
+
+	duCmd := &cobra.Command{
+		Use:   "du",
+		Short: "Show the disk usage of the project's docker resources",
+		Long: `Du sums the container layers, images and volumes labeled with the
+current project, broken down per service, and shows the machine wide build
+cache, with suggestions on how to reclaim space.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := du(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return duCmd
+}
+
+// serviceUsage is the disk used by the containers of a single service.
+type serviceUsage struct {
+	containers int64
+	images     map[string]int64
+}
+
+// du prints the disk usage report of the current project.
+func du() error {
+
+	// use the project the stack was started as, which --user-suffix changes
+	if st, err := readState(); err == nil && st != nil {
+		composeProject = st.Project
+	}
+
+	ids, err := dockerLines("ps", "-a", "-q", "--filter", projectFilter())
+	if err != nil {
+		return err
+	}
+
+	usage := make(map[string]*serviceUsage)
+	stopped := 0
+	for _, id := range ids {
+		info, err := dockerLines("inspect", "--size", "-f", `{{index .Config.Labels "com.docker.compose.service"}}	{{.SizeRw}}	{{.Image}}	{{.State.Running}}`, id)
+		if err != nil || len(info) == 0 {
+			continue
+		}
+
+		f := strings.Split(info[0], "\t")
+		if len(f) != 4 {
+			continue
+		}
+
+		u, ok := usage[f[0]]
+		if !ok {
+			u = &serviceUsage{images: make(map[string]int64)}
+			usage[f[0]] = u
+		}
+
+		size, _ := strconv.ParseInt(f[1], 10, 64)
+		u.containers += size
+
+		if _, seen := u.images[f[2]]; !seen {
+			u.images[f[2]] = imageSize(f[2])
+		}
+
+		if f[3] != "true" {
+			stopped++
+		}
+	}
+
+	var names []string
+	for name := range usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Project %s\n\n", stackProject())
+	fmt.Printf("%-20s %12s %12s\n", "SERVICE", "CONTAINERS", "IMAGES")
+
+	var total int64
+	counted := make(map[string]bool)
+	for _, name := range names {
+		u := usage[name]
+
+		var images int64
+		for image, size := range u.images {
+			images += size
+			if !counted[image] {
+				// services sharing an image only use its space once
+				counted[image] = true
+				total += size
+			}
+		}
+		total += u.containers
+
+		fmt.Printf("%-20s %12s %12s\n", name, humanSize(u.containers), humanSize(images))
+	}
+
+	volumes, err := projectVolumeSizes()
+	if err != nil {
+		return err
+	}
+	if len(volumes) > 0 {
+		fmt.Printf("\n%-33s %12s\n", "VOLUME", "SIZE")
+		for _, v := range volumes {
+			fmt.Printf("%-33s %12s\n", v.Name, v.Size)
+		}
+	}
+
+	fmt.Printf("\nContainers and images: %s\n", humanSize(total))
+
+	cache := buildCacheUsage()
+	if cache != "" {
+		fmt.Printf("Build cache (all projects): %s\n", cache)
+	}
+
+	var hints []string
+	if stopped > 0 {
+		hints = append(hints, fmt.Sprintf("%d stopped container(s), run `loadenv gc` to remove stale resources", stopped))
+	}
+	if cache != "" && !strings.HasPrefix(cache, "0B") {
+		hints = append(hints, "run `docker builder prune` to clear the build cache")
+	}
+	if len(hints) > 0 {
+		info("\n")
+		for _, hint := range hints {
+			info("hint: %s\n", hint)
+		}
+	}
+
+	return nil
+}
+
+// imageSize returns the size of an image in bytes, or 0 if it is unknown.
+func imageSize(image string) int64 {
+
+	info, err := dockerLines("image", "inspect", "-f", "{{.Size}}", image)
+	if err != nil || len(info) == 0 {
+		return 0
+	}
+
+	size, _ := strconv.ParseInt(info[0], 10, 64)
+
+	return size
+}
+
+// volumeUsage is a volume as reported by docker system df.
+type volumeUsage struct {
+	Name string
+	Size string
+}
+
+// projectVolumeSizes returns the sizes of the project's volumes. Docker
+// only computes volume sizes for system df.
+func projectVolumeSizes() ([]volumeUsage, error) {
+
+	names, err := dockerLines("volume", "ls", "-q", "--filter", projectFilter())
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	out, err := dockerLines("system", "df", "-v", "--format", "{{json .Volumes}}")
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+
+	var all []volumeUsage
+	if err := json.Unmarshal([]byte(out[0]), &all); err != nil {
+		return nil, fmt.Errorf("can not parse docker system df output: %v", err)
+	}
+
+	var volumes []volumeUsage
+	for _, v := range all {
+		if wanted[v.Name] {
+			volumes = append(volumes, v)
+		}
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+
+	return volumes, nil
+}
+
+// buildCacheUsage returns the build cache size and reclaimable space as
+// reported by docker, or "" if it is unknown.
+func buildCacheUsage() string {
+
+	lines, err := dockerLines("system", "df", "--format", "{{.Type}}\t{{.Size}}\t{{.Reclaimable}}")
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) == 3 && f[0] == "Build Cache" {
+			return f[1] + " (" + f[2] + " reclaimable)"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,388 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/shaybix/loadenv/pkg/loadenv"
+	"github.com/spf13/cobra"
+)
+
+// NewGetCmd returns the get command.
+func NewGetCmd() *cobra.Command {
+
+	getCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the value of a key in the dotenv file",
+		Long: `Get prints the value of key as written in the dotenv file, without
+expanding references or applying the other layers.
+
+With --describe the description of key is printed instead, from a
+"# @desc" comment above it, e.g.
+
+  # @desc the host of the MySQL server
+  DB_HOST=mysql
+
+or else from its description in ` + schemaFile + `. With --pretty a JSON
+value is printed indented.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := getKey(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	getCmd.Flags().BoolVar(&flags.getDescribe, "describe", false, "print the description of the key instead of its value")
+	getCmd.Flags().BoolVar(&flags.getPretty, "pretty", false, "pretty-print a JSON value")
+
+	return getCmd
+}
+
+// NewSetCmd returns the set command.
+func NewSetCmd() *cobra.Command {
+
+	setCmd := &cobra.Command{
+		Use:   "set <key=value>...",
+		Short: "Set keys in the dotenv file",
+		Long: `Set changes the value of each key in the dotenv file, e.g.
+loadenv set DB_HOST=mysql, keeping its comments and the order of the keys.
+Keys the file does not have yet are added at the end. Values are written
+quoted as needed and taken literally, so $ is not expanded.
+
+Keys marked protected in ` + schemaFile + ` are not changed directly: the
+change is written as a patch to changes.dir (default .loadenv/changes) for
+review, to be applied with git apply once approved.
+
+With --json-patch the JSON value of a single key is changed by an RFC 6902
+patch instead, so nested fields are edited without rewriting the value:
+
+  loadenv set FEATURES --json-patch '[{"op": "replace", "path": "/billing/enabled", "value": true}]'
+
+The add, remove, replace and test operations are supported. The file is
+left unchanged when one fails.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			patch := setKeys
+			if flags.setJSONPatch != "" {
+				patch = patchKey
+			}
+			if err := patch(args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	setCmd.Flags().StringVar(&flags.setJSONPatch, "json-patch", "", "change the JSON value of the key with a JSON patch")
+
+	return setCmd
+}
+
+// NewUnsetCmd returns the unset command.
+func NewUnsetCmd() *cobra.Command {
+
+	unsetCmd := &cobra.Command{
+		Use:   "unset <key>...",
+		Short: "Remove keys from the dotenv file",
+		Long: `Unset removes each key from the dotenv file along with the "# @"
+annotations above it, keeping the other lines as they are. Protected keys
+are handled like set does.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := unsetKeys(args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return unsetCmd
+}
+
+// getKey prints the value of key in the dotenv file, or its description
+// with --describe.
+func getKey(key string) error {
+
+	if flags.getDescribe {
+		return describeKey(key)
+	}
+
+	env, err := readEnvMap(dotenvFileName())
+	if err != nil {
+		return err
+	}
+
+	value, ok := env[key]
+	if !ok {
+		return fmt.Errorf("%s is not set in %s", key, dotenvFileName())
+	}
+
+	if flags.getPretty {
+		v, err := decodeJSON(value)
+		if err != nil {
+			return fmt.Errorf("can not pretty-print %s, it is not JSON: %v", key, err)
+		}
+		if value, err = encodeJSON(v, true); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(value)
+
+	return nil
+}
+
+// describeKey prints the description of key.
+func describeKey(key string) error {
+
+	s, err := readSchema()
+	if err != nil {
+		return err
+	}
+
+	descs, err := keyDescriptions(s)
+	if err != nil {
+		return err
+	}
+
+	if descs[key] == "" {
+		return fmt.Errorf("%s has no description, add a # @desc comment above it in %s", key, dotenvFileName())
+	}
+
+	fmt.Println(descs[key])
+
+	return nil
+}
+
+// setKeys sets every key=value of args in the dotenv file.
+func setKeys(args []string) error {
+
+	if err := checkReadOnly("set"); err != nil {
+		return err
+	}
+
+	var vars []dotenv.Var
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("can not set %q, use KEY=value", arg)
+		}
+		if !keyNameRe.MatchString(kv[0]) {
+			return fmt.Errorf("%q is not a valid key name", kv[0])
+		}
+		vars = append(vars, dotenv.Var{Key: kv[0], Value: kv[1]})
+	}
+
+	var keys []string
+	for _, v := range vars {
+		keys = append(keys, v.Key)
+	}
+
+	return editDotenv(keys, func(d *dotenv.Dialect, lines []dotenv.Line) ([]string, error) {
+
+		formatted := make(map[string]string)
+		for _, v := range vars {
+			line, err := d.Format(v, false)
+			if err != nil {
+				return nil, err
+			}
+			formatted[v.Key] = line
+		}
+
+		// the first definition of a key is replaced, any later ones
+		// would override it and are dropped
+		var out []string
+		done := make(map[string]bool)
+		for _, l := range lines {
+			if l.Var == nil || formatted[l.Var.Key] == "" {
+				out = append(out, l.Text)
+				continue
+			}
+			key := l.Var.Key
+			if !done[key] {
+				// keep an export prefix, the indentation and the comment
+				out = append(out, l.Text[:l.KeyOffset]+formatted[key]+l.Comment)
+				done[key] = true
+			}
+		}
+
+		for _, v := range vars {
+			if !done[v.Key] {
+				out = append(out, formatted[v.Key])
+				done[v.Key] = true
+			}
+		}
+
+		return out, nil
+	})
+}
+
+// patchKey changes the JSON value of the single key in args with the
+// --json-patch patch.
+func patchKey(args []string) error {
+
+	if len(args) != 1 || strings.Contains(args[0], "=") {
+		return fmt.Errorf("can not use --json-patch with %q, give a single key", strings.Join(args, " "))
+	}
+	key := args[0]
+
+	env, err := readEnvMap(dotenvFileName())
+	if err != nil {
+		return err
+	}
+
+	value, ok := env[key]
+	if !ok {
+		return fmt.Errorf("%s is not set in %s", key, dotenvFileName())
+	}
+
+	patched, err := applyJSONPatch(value, flags.setJSONPatch)
+	if err != nil {
+		return fmt.Errorf("can not patch %s: %v", key, err)
+	}
+
+	return setKeys([]string{key + "=" + patched})
+}
+
+// unsetKeys removes every key of args from the dotenv file.
+func unsetKeys(args []string) error {
+
+	if err := checkReadOnly("unset"); err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool)
+	for _, key := range args {
+		remove[key] = true
+	}
+
+	return editDotenv(args, func(d *dotenv.Dialect, lines []dotenv.Line) ([]string, error) {
+
+		found := make(map[string]bool)
+		var out []string
+		for _, l := range lines {
+			if l.Var == nil || !remove[l.Var.Key] {
+				out = append(out, l.Text)
+				continue
+			}
+			found[l.Var.Key] = true
+
+			// the annotations above belong to the removed key
+			for len(out) > 0 && isAnnotation(out[len(out)-1]) {
+				out = out[:len(out)-1]
+			}
+		}
+
+		for _, key := range args {
+			if !found[key] {
+				return nil, fmt.Errorf("%s is not set in %s", key, dotenvFileName())
+			}
+		}
+
+		return out, nil
+	})
+}
+
+// editDotenv rewrites the dotenv file with the lines edit returns for its
+// current lines. The file is replaced atomically, keeping its mode, and
+// created with mode 0600 when it does not exist. When keys, the keys edit
+// changes, include protected ones, the change is written for review
+// instead.
+func editDotenv(keys []string, edit func(d *dotenv.Dialect, lines []dotenv.Line) ([]string, error)) error {
+
+	fname := dotenvFileName()
+
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0600)
+	var lines []dotenv.Line
+
+	f, err := os.Open(fname)
+	if err == nil {
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		mode = fi.Mode().Perm()
+
+		lines, err = dotenv.ReadLines(f, fname, d)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	out, err := edit(d, lines)
+	if err != nil {
+		return err
+	}
+
+	protected, err := protectedKeys(keys)
+	if err != nil {
+		return err
+	}
+	if len(protected) > 0 {
+		var before, after []string
+		for _, l := range lines {
+			before = append(before, strings.Split(l.Text, "\n")...)
+		}
+		for _, line := range out {
+			after = append(after, strings.Split(line, "\n")...)
+		}
+		return writeChange(fname, before, after, protected)
+	}
+
+	var b bytes.Buffer
+	for _, line := range out {
+		fmt.Fprintln(&b, line)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fname), "."+filepath.Base(fname)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := keepAllowed(func() error { return os.Rename(tmp.Name(), fname) }); err != nil {
+		return err
+	}
+
+	return flags.events.Emit(loadenv.EnvChanged{File: fname, Keys: keys})
+}
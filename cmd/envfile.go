@@ -0,0 +1,98 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/viper"
+)
+
+// composeEnvFile holds the resolved environment handed to compose with
+// --env-file. It is removed with the rest of tmpDir when the stack stops.
+const composeEnvFile = tmpDir + "/env"
+
+var (
+
+	// loadedKeys are the variables loadenv resolved, in the order they
+	// were first set.
+	loadedKeys []string
+)
+
+// setEnv sets key in loadenv's environment and records it for the
+// compose env file.
+func setEnv(key, value string) error {
+
+	if err := os.Setenv(key, value); err != nil {
+		return err
+	}
+
+	for _, k := range loadedKeys {
+		if k == key {
+			return nil
+		}
+	}
+	loadedKeys = append(loadedKeys, key)
+
+	return nil
+}
+
+// inheritsEnv reports whether compose should read the variables from the
+// environment it inherits, as loadenv did before it wrote an env file.
+func inheritsEnv() bool {
+	return flags.inheritEnv || viper.GetBool("compose.inherit_env")
+}
+
+// writeComposeEnvFile writes the resolved variables to composeEnvFile.
+func writeComposeEnvFile() error {
+
+	var out bytes.Buffer
+	for _, key := range loadedKeys {
+		line, err := (*dotenv.Dialect)(nil).Format(envVar{Key: key, Value: os.Getenv(key)}, false)
+		if err != nil {
+			return fmt.Errorf("can not write %s to the compose env file: %v", key, err)
+		}
+		fmt.Fprintln(&out, line)
+	}
+
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(composeEnvFile, out.Bytes(), 0600)
+}
+
+// composeEnv returns the environment for a compose command reading
+// composeEnvFile: loadenv's own environment without the variables in the
+// file, so the file is the only source of their values.
+func composeEnv() ([]string, error) {
+
+	keys, err := keyLines(composeEnvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if _, ok := keys[strings.SplitN(kv, "=", 2)[0]]; !ok {
+			env = append(env, kv)
+		}
+	}
+
+	return env, nil
+}
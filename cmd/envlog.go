@@ -0,0 +1,152 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewEnvlogCmd returns the envlog command.
+func NewEnvlogCmd() *cobra.Command {
+
+	envlogCmd := &cobra.Command{
+		Use:   "envlog <rev1> <rev2>",
+		Short: "Report environment variable changes between two git revisions",
+		Long: `Envlog compares .env.example at two git revisions and reports the
+variables that were added, removed or renamed, for release notes and
+upgrade guides.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := envlog(os.Stdout, args[0], args[1]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	envlogCmd.Flags().StringVar(&flags.envlogFile, "file", ".env.example", "file to compare between the revisions")
+
+	return envlogCmd
+}
+
+// envAtRevision returns the variables of envlogFile at the git revision rev.
+func envAtRevision(rev string) (map[string]string, error) {
+
+	var stderr bytes.Buffer
+	showCmd := exec.Command("git", "show", rev+":./"+flags.envlogFile)
+	showCmd.Stderr = &stderr
+
+	out, err := showCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("can not read %s at %s: %s", flags.envlogFile, rev, strings.TrimSpace(stderr.String()))
+	}
+
+	vars, err := parseEnv(bytes.NewReader(out), rev+":"+flags.envlogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, v := range vars {
+		env[v.Key] = v.Value
+	}
+
+	return env, nil
+}
+
+// envlog writes the variables added, removed and renamed between the two
+// revisions to w. A removed and an added variable sharing the same
+// non-empty value are reported as a rename.
+func envlog(w io.Writer, rev1, rev2 string) error {
+
+	before, err := envAtRevision(rev1)
+	if err != nil {
+		return err
+	}
+
+	after, err := envAtRevision(rev2)
+	if err != nil {
+		return err
+	}
+
+	var added, removed []string
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	var renamed []string
+	renamedTo := make(map[string]bool)
+	var stillRemoved []string
+	for _, old := range removed {
+		found := false
+		for _, key := range added {
+			if !renamedTo[key] && before[old] != "" && before[old] == after[key] {
+				renamed = append(renamed, old+" -> "+key)
+				renamedTo[key] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			stillRemoved = append(stillRemoved, old)
+		}
+	}
+
+	var stillAdded []string
+	for _, key := range added {
+		if !renamedTo[key] {
+			stillAdded = append(stillAdded, key)
+		}
+	}
+
+	if len(stillAdded)+len(stillRemoved)+len(renamed) == 0 {
+		fmt.Fprintf(w, "No environment changes in %s between %s and %s\n", flags.envlogFile, rev1, rev2)
+		return nil
+	}
+
+	printSection(w, "Added", "+", stillAdded)
+	printSection(w, "Removed", "-", stillRemoved)
+	printSection(w, "Renamed", "~", renamed)
+
+	return nil
+}
+
+func printSection(w io.Writer, title, marker string, lines []string) {
+
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, line := range lines {
+		fmt.Fprintf(w, "  %s %s\n", marker, line)
+	}
+}
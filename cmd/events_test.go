@@ -0,0 +1,53 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shaybix/loadenv/pkg/loadenv"
+)
+
+func TestNewRootCmdEvents(t *testing.T) {
+
+	testProject(t)
+
+	var events loadenv.Events
+	var changed []loadenv.EnvChanged
+	var resolved []loadenv.Resolved
+	events.OnEnvChanged(func(ev loadenv.EnvChanged) error {
+		changed = append(changed, ev)
+		return nil
+	})
+	events.OnResolved(func(ev loadenv.Resolved) error {
+		resolved = append(resolved, ev)
+		return nil
+	})
+
+	for _, args := range [][]string{{"set", "LOADENV_TEST_A=1"}, {"validate", "--strict"}} {
+		root := NewRootCmd(Options{Events: &events})
+		root.SetArgs(args)
+		if err := root.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	if want := []loadenv.EnvChanged{{File: ".env", Keys: []string{"LOADENV_TEST_A"}}}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("EnvChanged events = %+v, want %+v", changed, want)
+	}
+	if len(resolved) != 1 || resolved[0].Vars["LOADENV_TEST_A"] != "1" {
+		t.Errorf("Resolved events = %+v, want one with LOADENV_TEST_A=1", resolved)
+	}
+}
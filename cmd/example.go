@@ -0,0 +1,117 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/cobra"
+)
+
+// NewExampleCmd returns the example command.
+func NewExampleCmd() *cobra.Command {
+
+	exampleCmd := &cobra.Command{
+		Use:   "example",
+		Short: "Write .env.example from the dotenv file with the values left out",
+		Long: `Example writes .env.example from the dotenv file, keeping its keys,
+comments and order but none of its values, so the example can be
+regenerated whenever keys are added instead of drifting. Keys with a
+default in ` + schemaFile + ` get the default, the others are left
+empty. Use -o - to print the example instead.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := writeExample(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	exampleCmd.Flags().StringVarP(&flags.exampleOutput, "output", "o", ".env.example", "file to write to, - for stdout")
+
+	return exampleCmd
+}
+
+// writeExample writes the example of the dotenv file to exampleOutput.
+func writeExample() error {
+
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	s, err := readSchema()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(dotenvFileName())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lines, err := dotenv.ReadLines(f, dotenvFileName(), d)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	keys := 0
+	for _, l := range lines {
+		if l.Var == nil {
+			fmt.Fprintln(&out, l.Text)
+			continue
+		}
+
+		v := dotenv.Var{Key: l.Var.Key}
+		if s != nil {
+			v.Value = s.Keys[v.Key].Default
+		}
+
+		line, err := d.Format(v, false)
+		if err != nil {
+			return err
+		}
+
+		// keep an export prefix and the indentation
+		fmt.Fprintln(&out, l.Text[:l.KeyOffset]+line+l.Comment)
+		keys++
+	}
+
+	if flags.exampleOutput == "-" {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+
+	if err := checkReadOnly("example"); err != nil {
+		return err
+	}
+
+	tmp := flags.exampleOutput + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, flags.exampleOutput); err != nil {
+		return err
+	}
+
+	info("Wrote %d key(s) to %s\n", keys, flags.exampleOutput)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+)
+
+// NewExecCmd returns the exec command.
+func NewExecCmd() *cobra.Command {
+
+	execCmd := &cobra.Command{
+		Use:   "exec <service> <command> [args...]",
+		Short: "Run a command in a running service with the loaded environment",
+		Long: `Exec runs a command in the container of a service of the running stack,
+e.g. loadenv exec app php artisan tinker, with the variables loadenv
+resolves added to its environment. It is interactive, with a TTY when
+loadenv runs in a terminal unless -T is given, and loadenv exits with the
+command's exit code.
+
+The variables are handed to docker by name, so their values do not show
+up in the process list.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := execService(args[0], args[1:]); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exit(exitErr.ExitCode())
+				}
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	// everything after the service belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().BoolVarP(&flags.execNoTTY, "no-tty", "T", false, "do not allocate a TTY")
+	execCmd.Flags().StringVarP(&flags.execUser, "user", "u", "", "user to run the command as")
+	execCmd.Flags().StringVarP(&flags.execWorkdir, "workdir", "w", "", "directory to run the command in")
+
+	return execCmd
+}
+
+// execService runs args in the container of service.
+func execService(service string, args []string) error {
+
+	st, err := attachState()
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return fmt.Errorf("no stack started by loadenv in the local directory")
+	}
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := applySchema(); err != nil {
+		return err
+	}
+
+	container, err := serviceContainer(service)
+	if err != nil {
+		return err
+	}
+
+	dargs := []string{"exec", "-i"}
+	if fi, err := os.Stdin.Stat(); !flags.execNoTTY && err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		dargs = append(dargs, "-t")
+	}
+	if flags.execUser != "" {
+		dargs = append(dargs, "--user", flags.execUser)
+	}
+	if flags.execWorkdir != "" {
+		dargs = append(dargs, "--workdir", flags.execWorkdir)
+	}
+	// docker takes the value of a bare -e KEY from its own environment
+	for _, key := range loadedKeys {
+		dargs = append(dargs, "-e", key)
+	}
+	dargs = append(append(dargs, container), args...)
+
+	c := exec.Command("docker", dargs...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return timePhase("run", func() error { return runAttached(c) })
+}
@@ -0,0 +1,113 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewExportCmd returns the export command.
+func NewExportCmd() *cobra.Command {
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the environment as shell commands setting it",
+		Long: `Export resolves the environment like run does and prints a command
+setting each variable, quoted for the shell chosen with --format, so it
+can be loaded into an interactive shell:
+
+  eval "$(loadenv export)"                       # bash, zsh
+  loadenv export --format fish | source          # fish
+  loadenv export --format powershell | Invoke-Expression`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := export(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	exportCmd.Flags().StringVar(&flags.exportFormat, "format", "sh", "shell to print commands for (sh|fish|powershell|cmd)")
+
+	return exportCmd
+}
+
+// exportFormats format the command setting key to value in each shell.
+var exportFormats = map[string]func(key, value string) (string, error){
+	"sh": func(key, value string) (string, error) {
+		return "export " + key + "=" + shellQuote(value), nil
+	},
+	"fish": func(key, value string) (string, error) {
+		quoted := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+		return "set -gx " + key + " '" + quoted + "'", nil
+	},
+	"powershell": func(key, value string) (string, error) {
+		return "$env:" + key + " = '" + strings.Replace(value, "'", "''", -1) + "'", nil
+	},
+	"cmd": func(key, value string) (string, error) {
+		// cmd has no way to quote a newline, and expands %VAR% even
+		// inside quotes
+		if strings.ContainsAny(value, "\r\n%") {
+			return "", fmt.Errorf("%s can not be represented in cmd", key)
+		}
+		return `set "` + key + "=" + value + `"`, nil
+	},
+}
+
+// exportVar formats the command setting key to value with format. Keys
+// that are not variable names are refused, as the shell would run them.
+func exportVar(format func(key, value string) (string, error), key, value string) (string, error) {
+
+	if !keyNameRe.MatchString(key) {
+		return "", fmt.Errorf("can not export %q, it is not a valid key name", key)
+	}
+
+	return format(key, value)
+}
+
+// export prints the resolved environment in exportFormat.
+func export() error {
+
+	format, ok := exportFormats[flags.exportFormat]
+	if !ok {
+		return fmt.Errorf("unknown format %q, use sh, fish, powershell or cmd", flags.exportFormat)
+	}
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := applySchema(); err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, key := range loadedKeys {
+		line, err := exportVar(format, key, os.Getenv(key))
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// faketimeFile keeps the fake clock between invocations.
+	faketimeFile = ".loadenv/faketime.json"
+	// faketimeOverride is the override file the fake clock is set in.
+	faketimeOverride = "docker-compose.faketime.yml"
+)
+
+func init() {
+	viper.SetDefault("faketime.lib", "/usr/lib/x86_64-linux-gnu/faketime/libfaketime.so.1")
+}
+
+// faketimeConfig is the content of the faketime file.
+type faketimeConfig struct {
+	Time     string   `json:"time"`
+	Services []string `json:"services"`
+}
+
+// NewFaketimeCmd returns the faketime command and its subcommands.
+func NewFaketimeCmd() *cobra.Command {
+
+	faketimeCmd := &cobra.Command{
+		Use:   "faketime",
+		Short: "Run services with a fake clock",
+		Long: `Faketime preloads libfaketime into services, so they see a different date
+than the host. The library must be installed in the image, at faketime.lib.
+The clock is kept until cleared and applied whenever the stack starts.`,
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <time>",
+		Short: "Set the clock, e.g. 2025-12-31, \"2025-12-31 23:59:00\" or +2d",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := setFaketime(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+	setCmd.Flags().StringSliceVarP(&flags.faketimeServices, "service", "s", nil, "services to fake the clock of (default is the app service)")
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Restore the real clock",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := clearFaketime(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	faketimeCmd.AddCommand(setCmd, clearCmd)
+
+	return faketimeCmd
+}
+
+// faketimeSpec returns the FAKETIME value for t. Dates start a clock
+// ticking from that moment, offsets like +2d are passed through.
+func faketimeSpec(t string) (string, error) {
+
+	if strings.HasPrefix(t, "+") || strings.HasPrefix(t, "-") {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, t); err == nil {
+			return "@" + parsed.Format("2006-01-02 15:04:05"), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid time %q, use YYYY-MM-DD, \"YYYY-MM-DD HH:MM:SS\" or an offset like +2d", t)
+}
+
+// setFaketime records the fake clock and recreates the affected services
+// of a running stack.
+func setFaketime(t string) error {
+
+	if err := checkReadOnly("faketime set"); err != nil {
+		return err
+	}
+
+	spec, err := faketimeSpec(t)
+	if err != nil {
+		return err
+	}
+
+	cfg := faketimeConfig{Time: spec, Services: flags.faketimeServices}
+	if len(cfg.Services) == 0 {
+		cfg.Services = []string{viper.GetString("app_service")}
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(faketimeFile), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(faketimeFile, b, 0644); err != nil {
+		return err
+	}
+
+	info("Faking the clock of %s at %s\n", strings.Join(cfg.Services, ", "), spec)
+
+	return applyFaketime(cfg.Services)
+}
+
+// clearFaketime removes the fake clock and recreates the services it was
+// applied to.
+func clearFaketime() error {
+
+	if err := checkReadOnly("faketime clear"); err != nil {
+		return err
+	}
+
+	cfg, err := readFaketime()
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	if err := os.Remove(faketimeFile); err != nil {
+		return err
+	}
+
+	return applyFaketime(cfg.Services)
+}
+
+// readFaketime reads the faketime file, returning nil when no fake
+// clock is set.
+func readFaketime() (*faketimeConfig, error) {
+
+	b, err := os.ReadFile(faketimeFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cfg faketimeConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", faketimeFile, err)
+	}
+
+	return &cfg, nil
+}
+
+// setupFaketime writes the override preloading libfaketime, if a fake
+// clock is set.
+func setupFaketime() error {
+
+	cfg, err := readFaketime()
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	services := make(map[string]interface{})
+	for _, name := range cfg.Services {
+		services[name] = map[string]interface{}{
+			"environment": map[string]string{
+				"LD_PRELOAD":          viper.GetString("faketime.lib"),
+				"FAKETIME":            cfg.Time,
+				"FAKETIME_DONT_RESET": "1",
+			},
+		}
+	}
+
+	return writeOverride(faketimeOverride, map[string]interface{}{
+		"services": services,
+	})
+}
+
+// applyFaketime updates the overrides of the running stack, if any, and
+// recreates services so they pick up the clock.
+func applyFaketime(services []string) error {
+	return reapplyOverride(faketimeOverride, setupFaketime, services)
+}
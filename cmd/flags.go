@@ -0,0 +1,238 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/shaybix/loadenv/pkg/loadenv"
+)
+
+// flagValues holds the values of the flags of a command tree, along with
+// the defaults given in its Options. NewRootCmd binds the flags of its tree
+// to a new set, which becomes the one in use when the tree runs, so several
+// trees can be built in one process.
+type flagValues struct {
+	// defaultDotenv is the dotenv file used when --dotenv is not given.
+	defaultDotenv string
+	// configName is the name of the config file, without extension.
+	configName string
+	// events are the callbacks given in the Options, nil without any.
+	events *loadenv.Events
+
+	// chaos
+	chaosLatency  []string
+	chaosKill     []string
+	chaosDuration time.Duration
+
+	// check
+	checkExample  string
+	checkExitCode bool
+
+	// compose
+	// composeChoice is the compose implementation chosen with --compose.
+	composeChoice string
+	// composeArgs are extra global options given with --compose-arg.
+	composeArgs []string
+
+	// convert
+	convertFrom   string
+	convertTo     string
+	convertOutput string
+
+	// copy
+	copyClearAfter time.Duration
+	copyClear      string
+
+	// dotenv dialect
+	dialectName string
+
+	// diff
+	diffShowValues bool
+
+	// docker
+	// dockerTimeout limits how long a single docker or compose command may
+	// run, set with --timeout or docker.timeout.
+	dockerTimeout time.Duration
+
+	// doctor
+	doctorFix bool
+
+	// down
+	downVolumes       bool
+	downRemoveOrphans bool
+	downPruneImages   string
+	downPruneCache    bool
+
+	// compose environment
+	inheritEnv bool
+
+	// envlog
+	envlogFile string
+
+	// example
+	exampleOutput string
+
+	// exec
+	execNoTTY   bool
+	execUser    string
+	execWorkdir string
+
+	// export
+	exportFormat string
+
+	// faketime
+	faketimeServices []string
+
+	// gc
+	gcOlderThan time.Duration
+	gcDryRun    bool
+	gcVolumes   bool
+
+	// get
+	getDescribe bool
+	getPretty   bool
+
+	// graph
+	graphFormat string
+
+	// project guard
+	forceProject bool
+
+	// history
+	historyLimit int
+
+	// messages
+	langFlag string
+
+	// import
+	importKeyCol         string
+	importValueCol       string
+	importTypeCol        string
+	importDescriptionCol string
+	importOutput         string
+	importSchema         bool
+
+	// instrument
+	instrumentOutput string
+
+	// dotenv layers
+	envName string
+
+	// logs
+	logsFollow     bool
+	logsTail       string
+	logsSince      string
+	logsTimestamps bool
+	logsNoColor    bool
+
+	// matrix
+	matrixReveal   bool
+	matrixOnlyDiff bool
+	matrixWidth    int
+
+	// profile
+	profileGlobal bool
+
+	// probe
+	probeStatus      int
+	probeBody        string
+	probeRetries     int
+	probeInterval    time.Duration
+	probeMaxInterval time.Duration
+	probeTimeout     time.Duration
+
+	// record
+	recordOutput string
+
+	// restart
+	restartRecreate bool
+
+	// the root command and up
+	cfgFile     string
+	dotenvFiles []string
+	withNode    bool
+	verifySigs  bool
+	userSuffix  bool
+	remoteHost  string
+	withSync    bool
+	perfMode    string
+	quiet       bool
+	verbose     bool
+	noExpand    bool
+
+	// run
+	runCleanEnv bool
+	runKeepEnv  []string
+
+	// sbom
+	sbomFormat string
+	sbomOutput string
+
+	// up --scan
+	scanBeforeUp bool
+
+	// search
+	searchReveal bool
+	searchLimit  int
+	searchCopy   bool
+	searchEdit   bool
+
+	// set
+	setJSONPatch string
+
+	// sign
+	signKey string
+
+	// status
+	statusJSON  bool
+	statusDrift bool
+
+	// timings
+	showTimings bool
+
+	// up --ttl
+	stackTTL time.Duration
+
+	// unused
+	unusedFromRuntime string
+
+	// up
+	upDetach     bool
+	upBuild      bool
+	upNoBuild    bool
+	upDownOnStop bool
+	// noDockerCheck skips checking for the Dockerfile a build needs.
+	noDockerCheck bool
+
+	// up --validate
+	validateOnUp bool
+
+	// validate
+	validateStrict bool
+
+	// up --wait
+	upWait             bool
+	upWaitTimeout      time.Duration
+	upWaitProbeTimeout time.Duration
+}
+
+// newFlagValues returns flag values with the default Options.
+func newFlagValues() *flagValues {
+	return &flagValues{defaultDotenv: ".env", configName: ".loadenv"}
+}
+
+// flags are the flag values of the command tree that is running, or that
+// subcommands built outside of NewRootCmd bind to.
+var flags = newFlagValues()
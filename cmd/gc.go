@@ -0,0 +1,194 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewGcCmd returns the gc command.
+func NewGcCmd() *cobra.Command {
+
+	gcCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove stale resources created by loadenv",
+		Long: `Gc removes the stopped containers of every loadenv project that have not
+run for longer than --older-than, the images they used when nothing else
+uses them, and reports the space reclaimed. With --volumes it also removes
+the loadenv volumes no container uses that were created before
+--older-than. Docker does not record when a volume was last used, and a
+stack stopped without down -v keeps its data in them, so they are only
+removed when asked for.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := gc(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	gcCmd.Flags().DurationVar(&flags.gcOlderThan, "older-than", 168*time.Hour, "only remove resources unused for longer than this")
+	gcCmd.Flags().BoolVar(&flags.gcDryRun, "dry-run", false, "only report what would be removed")
+	gcCmd.Flags().BoolVar(&flags.gcVolumes, "volumes", false, "also remove unused loadenv volumes, with the data of stopped stacks")
+
+	return gcCmd
+}
+
+// gc removes the stale loadenv resources.
+func gc() error {
+
+	if !flags.gcDryRun {
+		if err := checkReadOnly("gc"); err != nil {
+			return err
+		}
+	}
+
+	cutoff := time.Now().Add(-flags.gcOlderThan)
+	var total int64
+
+	ids, err := dockerLines("ps", "-a", "-q", "--filter", "label="+projectLabel, "--filter", "status=exited", "--filter", "status=created")
+	if err != nil {
+		return err
+	}
+
+	images := make(map[string]bool)
+	removed := make(map[string]bool)
+	for _, id := range ids {
+		info, err := dockerLines("inspect", "--size", "-f", "{{.Name}}\t{{.Created}}\t{{.State.FinishedAt}}\t{{.SizeRw}}\t{{.Image}}", id)
+		if err != nil || len(info) == 0 {
+			continue
+		}
+
+		f := strings.Split(info[0], "\t")
+		if len(f) != 5 {
+			continue
+		}
+
+		last, _ := time.Parse(time.RFC3339Nano, f[2])
+		if last.IsZero() || last.Year() < 2000 {
+			// never started
+			last, _ = time.Parse(time.RFC3339Nano, f[1])
+		}
+		if last.After(cutoff) {
+			continue
+		}
+
+		size, _ := strconv.ParseInt(f[3], 10, 64)
+		if err := gcRemove("container", strings.TrimPrefix(f[0], "/"), size, "rm", id); err != nil {
+			return err
+		}
+		total += size
+		images[f[4]] = true
+		removed[id] = true
+	}
+
+	for image := range images {
+		info, err := dockerLines("image", "inspect", "-f", "{{index .RepoTags 0}}\t{{.Size}}", image)
+		if err != nil || len(info) == 0 {
+			continue
+		}
+
+		f := strings.Split(info[0], "\t")
+		size, _ := strconv.ParseInt(f[len(f)-1], 10, 64)
+
+		// other containers may still use the image
+		used, _ := dockerLines("ps", "-a", "-q", "--filter", "ancestor="+image)
+		inUse := false
+		for _, id := range used {
+			if !removed[id] {
+				inUse = true
+			}
+		}
+		if inUse {
+			continue
+		}
+
+		if err := gcRemove("image", f[0], size, "image", "rm", image); err != nil {
+			return err
+		}
+		total += size
+	}
+
+	if flags.gcVolumes {
+		if err := gcVolumes(cutoff); err != nil {
+			return err
+		}
+	}
+
+	verb := "Reclaimed"
+	if flags.gcDryRun {
+		verb = "Would reclaim"
+	}
+	fmt.Printf("%s %s (volume sizes not included)\n", verb, humanSize(total))
+
+	return nil
+}
+
+// gcVolumes removes the loadenv volumes no container uses that were
+// created before cutoff.
+func gcVolumes(cutoff time.Time) error {
+
+	volumes, err := dockerLines("volume", "ls", "-q", "--filter", "label="+projectLabel, "--filter", "dangling=true")
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		info, err := dockerLines("volume", "inspect", "-f", "{{.CreatedAt}}", volume)
+		if err != nil || len(info) == 0 {
+			continue
+		}
+
+		// a date docker writes differently is not taken as old
+		created, err := time.Parse(time.RFC3339, info[0])
+		if err != nil || created.After(cutoff) {
+			continue
+		}
+
+		if err := gcRemove("volume", volume, -1, "volume", "rm", volume); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gcRemove reports and, unless --dry-run is given, removes a resource by
+// running docker with args. A negative size is not reported.
+func gcRemove(kind, name string, size int64, args ...string) error {
+
+	sz := ""
+	if size >= 0 {
+		sz = " (" + humanSize(size) + ")"
+	}
+
+	if flags.gcDryRun {
+		fmt.Printf("would remove %s %s%s\n", kind, name, sz)
+		return nil
+	}
+
+	if _, err := dockerLines(args...); err != nil {
+		return err
+	}
+
+	fmt.Printf("removed %s %s%s\n", kind, name, sz)
+
+	return nil
+}
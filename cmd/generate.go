@@ -0,0 +1,175 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// localDotenv holds the values generated for the checkout.
+const localDotenv = ".env.local"
+
+// generateConfig configures a generated variable.
+type generateConfig struct {
+	Generate string `mapstructure:"generate"`
+	Stable   bool   `mapstructure:"stable"`
+}
+
+// generatorRe matches a generator like password(24).
+var generatorRe = regexp.MustCompile(`^([a-z0-9]+)(?:\(([0-9]+)\))?$`)
+
+// setupGenerated sets the variables configured under generate that have
+// no value yet. Stable values are generated once and kept in .env.local,
+// so every checkout gets its own but they survive restarts. Viper lower
+// cases config keys, so the variable names are upper cased.
+func setupGenerated() error {
+
+	var configs map[string]generateConfig
+	if err := viper.UnmarshalKey("generate", &configs); err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	local := make(map[string]string)
+	if vars, err := parseEnvFile(localDotenv); err == nil {
+		for _, v := range vars {
+			local[v.Key] = v.Value
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var keys []string
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var persist []envVar
+	for _, key := range keys {
+		cfg := configs[key]
+		name := strings.ToUpper(key)
+
+		value, ok := local[name]
+		if !ok && os.Getenv(name) != "" {
+			continue
+		}
+
+		if !ok {
+			var err error
+			if value, err = generateValue(cfg.Generate); err != nil {
+				return fmt.Errorf("generate.%s: %v", key, err)
+			}
+			if cfg.Stable {
+				persist = append(persist, envVar{Key: name, Value: value})
+			}
+		}
+
+		if err := setEnv(name, value); err != nil {
+			return err
+		}
+	}
+
+	if len(persist) == 0 {
+		return nil
+	}
+
+	if err := checkReadOnly("saving generated values to " + localDotenv); err != nil {
+		return err
+	}
+
+	return keepAllowed(func() error { return appendGenerated(persist) })
+}
+
+// appendGenerated appends the generated vars to .env.local.
+func appendGenerated(vars []envVar) error {
+
+	f, err := os.OpenFile(localDotenv, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(f, "%s=%s\n", v.Key, v.Value); err != nil {
+			return err
+		}
+		info("Generated %s in %s\n", v.Key, localDotenv)
+	}
+
+	return f.Close()
+}
+
+// generateValue returns a random value made by the generator spec, one of
+// password(n), hex(n), base64(n) or uuid. n is in characters for passwords
+// and in bytes otherwise.
+func generateValue(spec string) (string, error) {
+
+	m := generatorRe.FindStringSubmatch(strings.TrimSpace(spec))
+	if m == nil {
+		return "", fmt.Errorf("invalid generator %q", spec)
+	}
+
+	n := 32
+	if m[2] != "" {
+		n, _ = strconv.Atoi(m[2])
+	}
+
+	switch m[1] {
+	case "password":
+		const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+		b := make([]byte, n)
+		for i := range b {
+			j, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+			if err != nil {
+				return "", err
+			}
+			b[i] = alphabet[j.Int64()]
+		}
+		return string(b), nil
+	case "hex", "base64", "uuid":
+		if m[1] == "uuid" {
+			n = 16
+		}
+		b := make([]byte, n)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		switch m[1] {
+		case "hex":
+			return hex.EncodeToString(b), nil
+		case "base64":
+			// without padding, = can not be read back by the default parser
+			return base64.RawURLEncoding.EncodeToString(b), nil
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+	}
+
+	return "", fmt.Errorf("unknown generator %q, use password, hex, base64 or uuid", m[1])
+}
@@ -0,0 +1,136 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// NewGraphCmd returns the graph command.
+func NewGraphCmd() *cobra.Command {
+
+	graphCmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Render the dependency graph of the environment variables",
+		Long: `Graph renders which variables reference other variables in their values
+and which compose services use which variables, as graphviz dot or mermaid.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := graph(os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	graphCmd.Flags().StringVar(&flags.graphFormat, "format", "dot", "output format (dot|mermaid)")
+
+	return graphCmd
+}
+
+// edge points from a variable or service to the variable it references.
+type edge struct {
+	from, to string
+	service  bool
+}
+
+// graph writes the variable dependency graph to w.
+func graph(w io.Writer) error {
+
+	if flags.graphFormat != "dot" && flags.graphFormat != "mermaid" {
+		return fmt.Errorf("unknown graph format %q", flags.graphFormat)
+	}
+
+	vars, err := parseEnvLayers(dotenvFileName())
+	if err != nil {
+		return err
+	}
+
+	var edges []edge
+	for _, v := range vars {
+		for _, ref := range varRefs(v.Value) {
+			edges = append(edges, edge{from: v.Key, to: ref})
+		}
+	}
+
+	if fname, err := findComposeFile(); err == nil {
+		refs, err := serviceVarRefs(fname)
+		if err != nil {
+			return err
+		}
+
+		var services []string
+		for name := range refs {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+
+		for _, name := range services {
+			for _, ref := range refs[name] {
+				edges = append(edges, edge{from: name, to: ref, service: true})
+			}
+		}
+	}
+
+	if flags.graphFormat == "mermaid" {
+		writeMermaid(w, edges)
+	} else {
+		writeDot(w, edges)
+	}
+
+	return nil
+}
+
+func writeDot(w io.Writer, edges []edge) {
+
+	fmt.Fprintln(w, "digraph loadenv {")
+
+	services := make(map[string]bool)
+	for _, e := range edges {
+		if e.service && !services[e.from] {
+			services[e.from] = true
+			fmt.Fprintf(w, "  %q [shape=box];\n", "service:"+e.from)
+		}
+	}
+
+	for _, e := range edges {
+		from := e.from
+		if e.service {
+			from = "service:" + from
+		}
+		fmt.Fprintf(w, "  %q -> %q;\n", from, e.to)
+	}
+
+	fmt.Fprintln(w, "}")
+}
+
+var mermaidIDRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func writeMermaid(w io.Writer, edges []edge) {
+
+	fmt.Fprintln(w, "graph LR")
+
+	for _, e := range edges {
+		from := e.from
+		if e.service {
+			from = fmt.Sprintf("svc_%s[%s]", mermaidIDRe.ReplaceAllString(e.from, "_"), e.from)
+		}
+		fmt.Fprintf(w, "  %s --> %s\n", from, e.to)
+	}
+}
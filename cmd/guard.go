@@ -0,0 +1,124 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// projectRoot returns the project.root config, relative to the config
+// file, or the working directory when it is not set.
+func projectRoot() (string, error) {
+
+	root := viper.GetString("project.root")
+	if root == "" {
+		return os.Getwd()
+	}
+
+	if !filepath.IsAbs(root) && viper.ConfigFileUsed() != "" {
+		root = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), root)
+	}
+
+	return filepath.Abs(root)
+}
+
+// realPath returns the absolute path of fname with symlinks resolved, as
+// far as it exists.
+func realPath(fname string) (string, error) {
+
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return "", err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+
+	return abs, nil
+}
+
+// guardProject keeps one project's secrets out of another: unless --force
+// is given it fails when the working directory is not the configured
+// project root or any of files lies outside the project, and it warns when
+// the state file was recorded by a different checkout.
+func guardProject(files ...string) error {
+
+	if flags.forceProject {
+		return nil
+	}
+
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+	if root, err = realPath(root); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if wd, err = realPath(wd); err != nil {
+		return err
+	}
+
+	if wd != root {
+		return fmt.Errorf("the working directory %s is not the project root %s, use --force to continue anyway", wd, root)
+	}
+
+	for _, fname := range files {
+		path, err := realPath(fname)
+		if err != nil {
+			return err
+		}
+
+		if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside the project %s, use --force to load it anyway", fname, root)
+		}
+	}
+
+	st, err := readState()
+	if err != nil || st == nil {
+		return err
+	}
+
+	if st.ProjectPath != "" && st.ProjectPath != root {
+		warn("%s was recorded for the project in %s, not this one\n", stateFile, st.ProjectPath)
+	}
+	if remote := gitRemote(); st.GitRemote != "" && remote != st.GitRemote {
+		warn("%s was recorded for the repository %s, not %s\n", stateFile, st.GitRemote, remote)
+	}
+
+	return nil
+}
+
+// gitRemote returns the URL of the origin remote of the working directory,
+// or "" when there is none.
+func gitRemote() string {
+
+	out, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
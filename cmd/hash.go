@@ -0,0 +1,135 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewHashCmd returns the hash command.
+func NewHashCmd() *cobra.Command {
+
+	hashCmd := &cobra.Command{
+		Use:   "hash",
+		Short: "Print a stable digest of the environment",
+		Long: `Hash prints a digest of the variables in the dotenv file that does not
+depend on their order. Secret values are included through an HMAC, so the
+digest can be stored in container labels without exposing them.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := printHash(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return hashCmd
+}
+
+// printHash prints the hash of the dotenv file.
+func printHash() error {
+
+	vars, err := parseEnvLayers(dotenvFileName())
+	if err != nil {
+		return err
+	}
+
+	h, err := envHash(vars)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(h)
+
+	return nil
+}
+
+// envHash returns the hex sha256 digest of vars, sorted by key, with the
+// values of secrets replaced by their HMAC.
+func envHash(vars []envVar) (string, error) {
+
+	key, err := hmacKey()
+	if err != nil {
+		return "", err
+	}
+
+	env := make(map[string]string)
+	for _, v := range vars {
+		env[v.Key] = v.Value
+	}
+
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		value := env[k]
+		if isSecret(k) {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(value))
+			value = "hmac:" + hex.EncodeToString(mac.Sum(nil))
+		}
+		fmt.Fprintf(h, "%s=%s\n", k, value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hmacKey returns the key secrets are hashed with: hash.hmac_key from the
+// config, or a random key generated once and kept in the home directory.
+func hmacKey() ([]byte, error) {
+
+	if key := viper.GetString("hash.hmac_key"); key != "" {
+		return []byte(key), nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	fname := filepath.Join(home, ".loadenv-hmac-key")
+	if b, err := os.ReadFile(fname); err == nil {
+		return []byte(strings.TrimSpace(string(b))), nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := hex.EncodeToString(b)
+
+	if err := os.WriteFile(fname, []byte(key+"\n"), 0600); err != nil {
+		return nil, err
+	}
+
+	return []byte(key), nil
+}
@@ -0,0 +1,448 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// historyFile records every loadenv invocation in the project, one JSON
+// entry per line.
+const historyFile = ".loadenv/history.log"
+
+// defaultHistorySize is how many entries are kept unless history.size is
+// set.
+const defaultHistorySize = 1000
+
+// historyEntry is a single recorded invocation.
+type historyEntry struct {
+	Time     time.Time     `json:"time"`
+	Args     []string      `json:"args"`
+	Env      string        `json:"env,omitempty"`
+	Duration time.Duration `json:"duration"`
+	ExitCode int           `json:"exit_code"`
+	// Masked is set when the values of secrets in Args were masked.
+	Masked bool `json:"masked,omitempty"`
+}
+
+// maskedValue replaces the values of secrets in the recorded arguments.
+const maskedValue = "***"
+
+var (
+
+	// runningCmd is the command being run, set once cobra has parsed the
+	// arguments.
+	runningCmd *cobra.Command
+)
+
+// unrecordedCommands, and their subcommands, are not added to the history,
+// so rerun 1 is always the last real command.
+var unrecordedCommands = map[string]bool{"history": true, "rerun": true, "help": true, "hook": true}
+
+// NewHistoryCmd returns the history command.
+func NewHistoryCmd() *cobra.Command {
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the loadenv commands run in the project",
+		Long: `History lists the loadenv commands recorded in ` + historyFile + `, oldest
+first, with how long they took and how they ended. The number in front of
+each is what loadenv rerun takes, 1 being the last command.
+
+The last history.size commands (default 1000) are kept, and nothing is
+recorded when history is false in the config.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listHistory(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	historyCmd.Flags().IntVarP(&flags.historyLimit, "limit", "n", 20, "number of commands to list, 0 for all")
+
+	return historyCmd
+}
+
+// NewRerunCmd returns the rerun command.
+func NewRerunCmd() *cobra.Command {
+
+	rerunCmd := &cobra.Command{
+		Use:   "rerun [n]",
+		Short: "Run a command from the history again",
+		Long: `Rerun runs the nth last command listed by loadenv history again, with the
+same arguments, and exits with its exit code. n defaults to 1, the last
+command. Secrets in the arguments, e.g. values of secret keys given to set
+or a password given to exec, are recorded masked, and those commands can
+not be rerun.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rerun(args); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exit(exitErr.ExitCode())
+				}
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return rerunCmd
+}
+
+// startCommand is run before every command.
+func startCommand(cmd *cobra.Command, args []string) {
+
+	runningCmd = cmd
+	startTiming(cmd, args)
+}
+
+// finishCommand is run after every command that succeeded.
+func finishCommand(cmd *cobra.Command, args []string) {
+
+	reportTimings(cmd, args)
+	recordHistory(0)
+}
+
+// exit records the command in the history and exits with code.
+func exit(code int) {
+
+	recordHistory(code)
+	os.Exit(code)
+}
+
+// recordHistory appends the running command, which ended with code, to
+// the history of the project. Directories without a dotenv file or a
+// .loadenv directory are not projects and get no history.
+func recordHistory(code int) {
+
+	if runningCmd == nil || runningCmd.Hidden || flags.copyClear != "" {
+		return
+	}
+	for c := runningCmd; c.HasParent(); c = c.Parent() {
+		if unrecordedCommands[c.Name()] {
+			return
+		}
+	}
+	if viper.IsSet("history") && !viper.GetBool("history") {
+		return
+	}
+
+	if _, err := os.Stat(filepath.Dir(historyFile)); err != nil {
+		if _, err := os.Stat(dotenvFileName()); err != nil {
+			return
+		}
+	}
+
+	args, masked := historyArgs()
+	entry := historyEntry{
+		Time:     commandStart,
+		Args:     args,
+		Env:      selectedEnv(),
+		Duration: time.Since(commandStart),
+		ExitCode: code,
+		Masked:   masked,
+	}
+
+	if err := appendHistory(entry); err != nil {
+		warn("can not record the command in %s: %v\n", historyFile, err)
+	}
+}
+
+// historyArgs returns the arguments of the running command to record,
+// relative to the root of its tree, with the secrets in them masked, and
+// whether any were. They are rebuilt from what cobra parsed, as os.Args
+// belong to the CLI the tree may be mounted under.
+func historyArgs() ([]string, bool) {
+
+	positional := runningCmd.Flags().Args()
+
+	// a JSON patch for a secret key holds its value
+	secretPatch := false
+	if runningCmd.Name() == "set" && runningCmd.Parent() == runningCmd.Root() {
+		for _, arg := range positional {
+			secretPatch = secretPatch || isSecret(strings.SplitN(arg, "=", 2)[0])
+		}
+	}
+
+	r := historySecrets().replacer(func(string) string { return maskedValue })
+	masked := false
+	mask := func(arg string) string {
+		m := maskArg(arg, r)
+		masked = masked || m != arg
+		return m
+	}
+
+	args := strings.Fields(runningCmd.CommandPath())[1:]
+	runningCmd.Flags().Visit(func(f *pflag.Flag) {
+		values := []string{f.Value.String()}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			values = sv.GetSlice()
+		}
+		for _, value := range values {
+			if f.Name == "json-patch" && secretPatch {
+				value, masked = maskedValue, true
+			}
+			args = append(args, "--"+f.Name+"="+mask(value))
+		}
+	})
+
+	dash := runningCmd.Flags().ArgsLenAtDash()
+	for i, arg := range positional {
+		if i == dash {
+			args = append(args, "--")
+		}
+		args = append(args, mask(arg))
+	}
+
+	return args, masked
+}
+
+// historySecrets returns the values of the secrets of the environment and
+// of the dotenv file and its layers, which the command may not have
+// loaded.
+func historySecrets() secretValues {
+
+	secrets := make(secretValues)
+	for _, kv := range os.Environ() {
+		if parts := strings.SplitN(kv, "=", 2); isSecret(parts[0]) {
+			secrets.add(parts[1])
+		}
+	}
+
+	d, err := selectedDialect()
+	if err != nil {
+		return secrets
+	}
+	for _, layer := range dotenvLayers(dotenvFileName()) {
+		if b, err := os.ReadFile(layer); err == nil {
+			secrets.addDotenv(b, layer, d)
+		}
+	}
+
+	return secrets
+}
+
+// maskArg masks the secrets in arg, the way record redacts them: the
+// value of KEY=value when KEY is a secret, the values of secrets replaced
+// by r, and the userinfo of URLs.
+func maskArg(arg string, r *strings.Replacer) string {
+
+	if kv := strings.SplitN(arg, "=", 2); len(kv) == 2 && keyNameRe.MatchString(kv[0]) && isSecret(kv[0]) {
+		return kv[0] + "=" + maskedValue
+	}
+
+	return userinfoRe.ReplaceAllString(r.Replace(arg), "${1}"+maskedValue+"@")
+}
+
+// appendHistory appends entry to the history file, dropping the oldest
+// entries beyond history.size.
+func appendHistory(entry historyEntry) error {
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0755); err != nil {
+		return err
+	}
+
+	// the arguments may contain values, so the history is private
+	f, err := os.OpenFile(historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	size := defaultHistorySize
+	if viper.IsSet("history.size") {
+		size = viper.GetInt("history.size")
+	}
+
+	lines, err := historyLines()
+	if err != nil || len(lines) <= size {
+		return err
+	}
+
+	tmp := historyFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines[len(lines)-size:], "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, historyFile)
+}
+
+// historyLines returns the non-empty lines of the history file.
+func historyLines() ([]string, error) {
+
+	f, err := os.Open(historyFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// readHistory returns the recorded entries, oldest first.
+func readHistory() ([]historyEntry, error) {
+
+	lines, err := historyLines()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []historyEntry
+	for i, line := range lines {
+		var e historyEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("can not parse line %d of %s: %v", i+1, historyFile, err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// listHistory prints the last historyLimit entries.
+func listHistory() error {
+
+	entries, err := readHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		info("No commands recorded in %s\n", historyFile)
+		return nil
+	}
+
+	start := 0
+	if flags.historyLimit > 0 && len(entries) > flags.historyLimit {
+		start = len(entries) - flags.historyLimit
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "N\tSTARTED\tTOOK\tRESULT\tENV\tCOMMAND")
+	for i := start; i < len(entries); i++ {
+		e := entries[i]
+
+		result := "ok"
+		if e.ExitCode != 0 {
+			result = fmt.Sprintf("exit %d", e.ExitCode)
+		}
+		env := e.Env
+		if env == "" {
+			env = "-"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", len(entries)-i, e.Time.Format("2006-01-02 15:04"),
+			e.Duration.Round(time.Second), result, env, commandLine(e.Args))
+	}
+
+	return w.Flush()
+}
+
+var plainArgRe = regexp.MustCompile(`^[-_./=:,@%+A-Za-z0-9]+$`)
+
+// commandLine returns the command line for args, recorded relative to the
+// root of the tree, quoting the arguments that need it.
+func commandLine(args []string) string {
+
+	words := []string{"loadenv"}
+	if runningCmd != nil {
+		words[0] = runningCmd.Root().Name()
+	}
+	for _, arg := range args {
+		if !plainArgRe.MatchString(arg) {
+			arg = shellQuote(arg)
+		}
+		words = append(words, arg)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// rerun runs the nth last recorded command again.
+func rerun(args []string) error {
+
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("can not rerun %q, give the number loadenv history lists", args[0])
+		}
+	}
+
+	entries, err := readHistory()
+	if err != nil {
+		return err
+	}
+	if n > len(entries) {
+		return fmt.Errorf("there are only %d command(s) in %s", len(entries), historyFile)
+	}
+
+	entry := entries[len(entries)-n]
+	if entry.Masked {
+		return fmt.Errorf("can not rerun %s, the values of its secrets were not recorded", commandLine(entry.Args))
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	info("Running %s\n", commandLine(entry.Args))
+
+	c := exec.Command(self, entry.Args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return c.Run()
+}
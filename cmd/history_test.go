@@ -0,0 +1,70 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestHistoryArgs(t *testing.T) {
+
+	testProject(t)
+	if err := os.WriteFile(".env", []byte("DB_PASSWORD=hunter22\nAPP_NAME=shop\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		want   []string
+		masked bool
+	}{
+		{
+			args: []string{"up", "-d", "--", "--force-recreate"},
+			want: []string{"up", "--detach=true", "--", "--force-recreate"},
+		},
+		{
+			args:   []string{"exec", "--user", "root", "db", "mysql", "-phunter22", "postgres://me:pw@db/shop"},
+			want:   []string{"exec", "--user=root", "db", "mysql", "-p***", "postgres://***@db/shop"},
+			masked: true,
+		},
+		{
+			args:   []string{"set", "--dotenv", ".env", "DB_PASSWORD=new", "APP_NAME=store"},
+			want:   []string{"set", "--dotenv=.env", "DB_PASSWORD=***", "APP_NAME=store"},
+			masked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		root := NewRootCmd(Options{})
+		viper.Set("secrets.patterns", []string{"*PASSWORD*"})
+		cmd, rest, err := root.Find(tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags(rest); err != nil {
+			t.Fatal(err)
+		}
+		runningCmd = cmd
+
+		got, masked := historyArgs()
+		if !reflect.DeepEqual(got, tt.want) || masked != tt.masked {
+			t.Errorf("historyArgs() for %q = %q, %v, want %q, %v", tt.args, got, masked, tt.want, tt.masked)
+		}
+	}
+	runningCmd = nil
+}
@@ -0,0 +1,216 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hookShell is how the shell hook is written for one shell.
+type hookShell struct {
+	// format sets an exported variable, as export --format does.
+	format string
+	// local sets a variable of the shell only.
+	local func(key, value string) string
+	// unset removes a variable.
+	unset func(key string) string
+	// script installs the hook.
+	script string
+}
+
+var hookShells = map[string]hookShell{
+	"bash": {
+		format: "sh",
+		local:  func(key, value string) string { return key + "=" + shellQuote(value) },
+		unset:  func(key string) string { return "unset " + key },
+		script: `_loadenv_hook() {
+  if [ -n "$__LOADENV_DIR" ]; then
+    case "$PWD/" in "$__LOADENV_DIR"/*) return ;; esac
+    eval "$__LOADENV_RESTORE"
+    unset __LOADENV_DIR __LOADENV_RESTORE
+  fi
+  eval "$(command loadenv hook load bash)"
+}
+cd() { builtin cd "$@" && _loadenv_hook; }
+_loadenv_hook
+`,
+	},
+	"zsh": {
+		format: "sh",
+		local:  func(key, value string) string { return key + "=" + shellQuote(value) },
+		unset:  func(key string) string { return "unset " + key },
+		script: `_loadenv_hook() {
+  if [[ -n "$__LOADENV_DIR" ]]; then
+    [[ "$PWD/" == "$__LOADENV_DIR"/* ]] && return
+    eval "$__LOADENV_RESTORE"
+    unset __LOADENV_DIR __LOADENV_RESTORE
+  fi
+  eval "$(command loadenv hook load zsh)"
+}
+autoload -U add-zsh-hook
+add-zsh-hook chpwd _loadenv_hook
+_loadenv_hook
+`,
+	},
+	"fish": {
+		format: "fish",
+		local: func(key, value string) string {
+			return "set -g " + key + " '" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+		},
+		unset: func(key string) string { return "set -e " + key },
+		script: `function _loadenv_hook --on-variable PWD
+  if set -q __LOADENV_DIR
+    string match -q -- "$__LOADENV_DIR/*" "$PWD/"; and return
+    eval $__LOADENV_RESTORE
+    set -e __LOADENV_DIR __LOADENV_RESTORE
+  end
+  command loadenv hook load fish | source
+end
+_loadenv_hook
+`,
+	},
+}
+
+// NewHookCmd returns the hook command and its subcommands.
+func NewHookCmd() *cobra.Command {
+
+	hookCmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Integrate loadenv with the interactive shell",
+	}
+
+	shellCmd := &cobra.Command{
+		Use:   "shell <bash|zsh|fish>",
+		Short: "Print the hook loading the project environment on cd",
+		Long: `Shell prints a hook that loads the environment of a project into the
+interactive shell when it changes into the project directory, and
+restores the variables it changed when it leaves, like direnv does:
+
+  eval "$(loadenv hook shell bash)"    # ~/.bashrc
+  eval "$(loadenv hook shell zsh)"     # ~/.zshrc
+  loadenv hook shell fish | source     # ~/.config/fish/config.fish
+
+A dotenv file can set PATH or anything else the shell runs with, so only
+directories trusted with loadenv allow are loaded.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			sh, ok := hookShells[args[0]]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown shell %q, use bash, zsh or fish\n", args[0])
+				exit(1)
+			}
+			fmt.Print(sh.script)
+		},
+	}
+
+	// load is what the hook runs in every directory it changes into
+	loadCmd := &cobra.Command{
+		Use:    "load <bash|zsh|fish>",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := hookLoad(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, "loadenv:", err)
+				exit(1)
+			}
+		},
+	}
+
+	hookCmd.AddCommand(shellCmd, loadCmd)
+
+	return hookCmd
+}
+
+// hookLoad prints the commands loading the environment of the working
+// directory into shell, along with the commands restoring the variables
+// they change, or nothing when the directory is not an allowed project.
+func hookLoad(shell string) error {
+
+	sh, ok := hookShells[shell]
+	if !ok {
+		return fmt.Errorf("unknown shell %q, use bash, zsh or fish", shell)
+	}
+	flags.quiet = true
+
+	if _, err := os.Stat(dotenvFileName()); err != nil {
+		return nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	dir, err := realPath(wd)
+	if err != nil {
+		return err
+	}
+
+	ok, err = dirAllowed(dir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "loadenv: %s in %s is not loaded, review it and run loadenv allow\n", dotenvFileName(), dir)
+		return nil
+	}
+
+	before := make(map[string]string)
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		before[parts[0]] = parts[1]
+	}
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := applySchema(); err != nil {
+		return err
+	}
+
+	format := exportFormats[sh.format]
+	var restore []string
+	for _, key := range loadedKeys {
+		line, err := exportVar(format, key, os.Getenv(key))
+		if err != nil {
+			return err
+		}
+		fmt.Println(line)
+
+		if value, ok := before[key]; ok {
+			line, err := exportVar(format, key, value)
+			if err != nil {
+				return err
+			}
+			restore = append(restore, line)
+		} else {
+			restore = append(restore, sh.unset(key))
+		}
+	}
+
+	// the shell compares its own $PWD, which keeps symlinks, with this
+	shellDir := os.Getenv("PWD")
+	if shellDir == "" {
+		shellDir = wd
+	}
+
+	fmt.Println(sh.local("__LOADENV_RESTORE", strings.Join(restore, "; ")))
+	fmt.Println(sh.local("__LOADENV_DIR", shellDir))
+
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Translations are kept in locales/active.<lang>.yaml, by message ID, with
+// the one and other forms of plural messages, e.g.
+//
+//	DoctorOK: ok
+//	DoctorProblems:
+//	  one: doctor found {{.Count}} problem
+//	  other: doctor found {{.Count}} problems
+//
+// active.en.yaml lists the English messages for translators to copy. go
+// generate extracts it from the message literals of the source, and the
+// tests fail when it is out of date.
+
+//go:generate go test -run TestActiveMessages -update
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// message is a message to translate, in English.
+type message struct {
+	ID    string
+	One   string
+	Other string
+}
+
+// translations are the loaded messages of the selected language by ID.
+var translations map[string]message
+
+// tr returns msg translated to the selected language, filled in with
+// data. The one form is used when data has a Count of 1. Messages without
+// a translation are returned in English.
+func tr(msg message, data map[string]interface{}) string {
+
+	if translations == nil {
+		translations = loadTranslations(selectedLanguage())
+	}
+	if t, ok := translations[msg.ID]; ok {
+		msg = t
+	}
+
+	text := msg.Other
+	if count, ok := data["Count"]; ok && fmt.Sprint(count) == "1" && msg.One != "" {
+		text = msg.One
+	}
+
+	t, err := template.New(msg.ID).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return text
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return text
+	}
+
+	return b.String()
+}
+
+// loadTranslations loads the messages of lang, e.g. de-DE, falling back
+// to its base language, de, from the bundled translations and those in
+// i18n.dir, which take precedence.
+func loadTranslations(lang string) map[string]message {
+
+	messages := make(map[string]message)
+	base := strings.SplitN(lang, "-", 2)[0]
+
+	var names []string
+	for _, l := range []string{base, lang} {
+		names = append(names, "active."+l+".yaml", l+".yaml")
+	}
+
+	load := func(name string, read func(string) ([]byte, error)) {
+		b, err := read(name)
+		if os.IsNotExist(err) {
+			return
+		}
+		if err == nil {
+			err = parseTranslations(b, messages)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: can not load translations %s: %v\n", name, err)
+		}
+	}
+
+	for _, name := range names {
+		load("locales/"+name, localeFiles.ReadFile)
+	}
+	if dir := viper.GetString("i18n.dir"); dir != "" {
+		for _, name := range names {
+			load(filepath.Join(dir, name), os.ReadFile)
+		}
+	}
+
+	return messages
+}
+
+// parseTranslations adds the messages of a translation file to messages.
+func parseTranslations(b []byte, messages map[string]message) error {
+
+	var file map[string]interface{}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return err
+	}
+
+	for id, v := range file {
+		switch v := v.(type) {
+		case string:
+			messages[id] = message{ID: id, Other: v}
+		case map[interface{}]interface{}:
+			m := message{ID: id}
+			m.One, _ = v["one"].(string)
+			m.Other, _ = v["other"].(string)
+			messages[id] = m
+		default:
+			return fmt.Errorf("%s is neither a message nor one and other forms", id)
+		}
+	}
+
+	return nil
+}
+
+// selectedLanguage returns the BCP 47 tag of the language to print
+// messages in.
+func selectedLanguage() string {
+
+	if flags.langFlag != "" {
+		return flags.langFlag
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+
+		// de_DE.UTF-8@euro is de-DE
+		v = strings.SplitN(strings.SplitN(v, ".", 2)[0], "@", 2)[0]
+		if v == "C" || v == "POSIX" {
+			return "en"
+		}
+		return strings.Replace(v, "_", "-", -1)
+	}
+
+	return "en"
+}
@@ -0,0 +1,160 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var updateLocales = flag.Bool("update", false, "rewrite locales/active.en.yaml from the messages in the source")
+
+// sourceMessages returns the message literals of the package by ID.
+func sourceMessages(t *testing.T) map[string]message {
+
+	fnames, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	messages := make(map[string]message)
+	for _, fname := range fnames {
+		if strings.HasSuffix(fname, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, fname, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if ident, ok := lit.Type.(*ast.Ident); !ok || ident.Name != "message" {
+				return true
+			}
+
+			// messages built at run time, from translation files,
+			// have an ID that is not a literal
+			var m message
+			for _, elt := range lit.Elts {
+				kv := elt.(*ast.KeyValueExpr)
+				s, ok := kv.Value.(*ast.BasicLit)
+				if !ok {
+					if kv.Key.(*ast.Ident).Name == "ID" {
+						return true
+					}
+					t.Errorf("%s: message texts must be string literals", fset.Position(kv.Pos()))
+					continue
+				}
+				value, err := strconv.Unquote(s.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch kv.Key.(*ast.Ident).Name {
+				case "ID":
+					m.ID = value
+				case "One":
+					m.One = value
+				case "Other":
+					m.Other = value
+				}
+			}
+
+			if m.ID == "" || m.Other == "" {
+				t.Errorf("%s: message without an ID or Other text", fset.Position(lit.Pos()))
+			} else if seen, ok := messages[m.ID]; ok && seen != m {
+				t.Errorf("%s: message %s has a different text elsewhere", fset.Position(lit.Pos()), m.ID)
+			}
+			messages[m.ID] = m
+			return true
+		})
+	}
+
+	return messages
+}
+
+// TestActiveMessages checks that locales/active.en.yaml, which translators
+// copy, lists exactly the messages of the source. go generate rewrites it.
+func TestActiveMessages(t *testing.T) {
+
+	file := make(map[string]interface{})
+	for id, m := range sourceMessages(t) {
+		if m.One == "" {
+			file[id] = m.Other
+		} else {
+			file[id] = map[string]string{"one": m.One, "other": m.Other}
+		}
+	}
+	want, err := yaml.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fname := filepath.Join("locales", "active.en.yaml")
+	if *updateLocales {
+		if err := os.WriteFile(fname, want, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	got, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is not up to date with the messages of the source, run go generate ./cmd", fname)
+	}
+}
+
+// TestTranslations checks that the bundled translations only translate
+// messages of the source.
+func TestTranslations(t *testing.T) {
+
+	messages := sourceMessages(t)
+
+	fnames, err := filepath.Glob(filepath.Join("locales", "active.*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fname := range fnames {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		translated := make(map[string]message)
+		if err := parseTranslations(b, translated); err != nil {
+			t.Errorf("%s: %v", fname, err)
+		}
+		for id := range translated {
+			if _, ok := messages[id]; !ok {
+				t.Errorf("%s: %s is not a message of the source", fname, id)
+			}
+		}
+	}
+}
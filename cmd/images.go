@@ -0,0 +1,219 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// baseImages returns the images the stack is built from or runs: the FROM
+// images of every Dockerfile and the image of every service that is not
+// built, sorted and without duplicates.
+func baseImages() ([]string, error) {
+
+	seen := make(map[string]bool)
+	dockerfiles := make(map[string]bool)
+	if _, err := os.Stat("Dockerfile"); err == nil {
+		dockerfiles["Dockerfile"] = true
+	}
+
+	for _, fname := range composeFiles() {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, svc := range c.Services {
+			switch build := svc.Build.(type) {
+			case nil:
+				if svc.Image != "" {
+					seen[svc.Image] = true
+				}
+			case string:
+				dockerfiles[filepath.Join(build, "Dockerfile")] = true
+			case map[interface{}]interface{}:
+				context, _ := build["context"].(string)
+				dockerfile, _ := build["dockerfile"].(string)
+				if dockerfile == "" {
+					dockerfile = "Dockerfile"
+				}
+				dockerfiles[filepath.Join(context, dockerfile)] = true
+			}
+		}
+	}
+
+	for fname := range dockerfiles {
+		images, err := dockerfileBases(fname)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			seen[image] = true
+		}
+	}
+
+	var images []string
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	return images, nil
+}
+
+// dockerfileBases returns the images the FROM lines of a Dockerfile refer
+// to, with ARG defaults and the environment expanded. Earlier build stages
+// and scratch are skipped.
+func dockerfileBases(fname string) ([]string, error) {
+
+	f, err := os.Open(fname)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	args := make(map[string]string)
+	stages := map[string]bool{"scratch": true}
+	var images []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			kv := strings.SplitN(fields[1], "=", 2)
+			if len(kv) == 2 {
+				args[kv[0]] = strings.Trim(kv[1], `"'`)
+			}
+		case "FROM":
+			fields = fields[1:]
+			for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+				fields = fields[1:]
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			image := os.Expand(fields[0], func(name string) string {
+				if v, ok := os.LookupEnv(name); ok {
+					return v
+				}
+				return args[name]
+			})
+			if !stages[strings.ToLower(image)] {
+				images = append(images, image)
+			}
+			if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+				stages[strings.ToLower(fields[2])] = true
+			}
+		}
+	}
+
+	return images, scanner.Err()
+}
+
+// normalizeImage returns the repository of an image reference with the
+// docker hub defaults filled in, e.g. php:8.2 is docker.io/library/php.
+func normalizeImage(ref string) string {
+
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io/library/" + ref
+	}
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "docker.io/" + ref
+	}
+
+	return ref
+}
+
+// imageAllowed reports whether image comes from one of the registries or
+// namespaces listed, e.g. docker.io/library or ghcr.io/acme.
+func imageAllowed(image string, allowed []string) bool {
+
+	repo := normalizeImage(image)
+	for _, a := range allowed {
+		if repo == a || strings.HasPrefix(repo, a+"/") || matchesAny(repo, []string{a}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkBaseImages reports the base images not from images.allowed.
+func checkBaseImages() (string, error) {
+
+	allowed := viper.GetStringSlice("images.allowed")
+	if len(allowed) == 0 {
+		return "", nil
+	}
+
+	images, err := baseImages()
+	if err != nil {
+		return "", err
+	}
+
+	var denied []string
+	for _, image := range images {
+		if !imageAllowed(image, allowed) {
+			denied = append(denied, image)
+		}
+	}
+
+	if len(denied) > 0 {
+		return tr(message{ID: "DoctorImagesDenied", Other: "{{.Images}} not from an approved registry in images.allowed"},
+			map[string]interface{}{"Images": strings.Join(denied, ", ")}), nil
+	}
+
+	return "", nil
+}
+
+// enforceBaseImages fails when base images are not approved and
+// images.strict is set or loadenv runs in CI, and warns otherwise.
+func enforceBaseImages() error {
+
+	problem, err := checkBaseImages()
+	if err != nil || problem == "" {
+		return err
+	}
+
+	if viper.GetBool("images.strict") || os.Getenv("CI") != "" {
+		return fmt.Errorf("%s", problem)
+	}
+
+	warn("%s\n", problem)
+
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// NewImportCmd returns the import command and its subcommands.
+func NewImportCmd() *cobra.Command {
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Convert variables kept in other formats into a dotenv file",
+	}
+
+	importCmd.AddCommand(newImportCsvCmd())
+
+	return importCmd
+}
+
+// newImportCsvCmd returns the import csv command.
+func newImportCsvCmd() *cobra.Command {
+
+	importCsvCmd := &cobra.Command{
+		Use:   "csv <file>",
+		Short: "Convert a CSV export of a spreadsheet into a dotenv file",
+		Long: `Import csv reads a CSV file with a header row and writes every row as a
+variable, named by the --key-col column and set to the --value-col column,
+quoted for the dialect chosen with --dialect. Values are written literally,
+a $ in a spreadsheet cell is never expanded.
+
+With --schema it also writes ` + schemaFile + `, using the --type-col and
+--description-col columns when given and guessing the types otherwise.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := importCsv(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	importCsvCmd.Flags().StringVar(&flags.importKeyCol, "key-col", "key", "column holding the variable names")
+	importCsvCmd.Flags().StringVar(&flags.importValueCol, "value-col", "value", "column holding the values")
+	importCsvCmd.Flags().StringVar(&flags.importTypeCol, "type-col", "", "column holding the schema types")
+	importCsvCmd.Flags().StringVar(&flags.importDescriptionCol, "description-col", "", "column holding the descriptions")
+	importCsvCmd.Flags().StringVarP(&flags.importOutput, "output", "o", "", "file to write to (default is stdout)")
+	importCsvCmd.Flags().BoolVar(&flags.importSchema, "schema", false, "also write "+schemaFile)
+
+	return importCsvCmd
+}
+
+// keyNameRe matches the variable names every dialect accepts.
+var keyNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// importCsv converts fname and writes the result.
+func importCsv(fname string) error {
+
+	if flags.importOutput != "" || flags.importSchema {
+		if err := checkReadOnly("import csv"); err != nil {
+			return err
+		}
+	}
+
+	if flags.importSchema {
+		if _, err := os.Stat(schemaFile); err == nil {
+			return fmt.Errorf("%s already exists, remove it to generate a new one", schemaFile)
+		}
+	}
+
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%s is empty", fname)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := columns[name]
+		if !ok {
+			return 0, fmt.Errorf("%s has no %q column", fname, name)
+		}
+		return i, nil
+	}
+
+	keyCol, err := column(flags.importKeyCol)
+	if err != nil {
+		return err
+	}
+	valueCol, err := column(flags.importValueCol)
+	if err != nil {
+		return err
+	}
+	typeCol, err := column(flags.importTypeCol)
+	if err != nil {
+		return err
+	}
+	descriptionCol, err := column(flags.importDescriptionCol)
+	if err != nil {
+		return err
+	}
+
+	cell := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	var out bytes.Buffer
+	s := schema{Keys: make(map[string]schemaKey)}
+	seen := make(map[string]int)
+
+	for n, row := range rows[1:] {
+		line := n + 2
+
+		key := strings.TrimSpace(cell(row, keyCol))
+		if key == "" {
+			continue
+		}
+		if !keyNameRe.MatchString(key) {
+			return fmt.Errorf("%s:%d: %q is not a valid variable name", fname, line, key)
+		}
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("%s:%d: %s is already defined on line %d", fname, line, key, first)
+		}
+		seen[key] = line
+
+		v := envVar{Key: key, Value: cell(row, valueCol)}
+		formatted, err := d.Format(v, false)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", fname, line, err)
+		}
+		fmt.Fprintln(&out, formatted)
+
+		k := schemaKey{
+			Type:        strings.TrimSpace(cell(row, typeCol)),
+			Required:    true,
+			Description: strings.TrimSpace(cell(row, descriptionCol)),
+		}
+		if k.Type == "" {
+			k.Type = inferType(key, v.Value)
+		}
+		s.Keys[key] = k
+	}
+
+	if flags.importOutput == "" {
+		if _, err := os.Stdout.Write(out.Bytes()); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(flags.importOutput, out.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	if !flags.importSchema {
+		return nil
+	}
+
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(schemaFile, b, 0644); err != nil {
+		return err
+	}
+
+	info("Wrote %s, review the guessed types before committing it\n", schemaFile)
+
+	return nil
+}
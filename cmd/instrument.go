@@ -0,0 +1,115 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// NewInstrumentCmd returns the instrument command and its subcommands.
+func NewInstrumentCmd() *cobra.Command {
+
+	instrumentCmd := &cobra.Command{
+		Use:   "instrument",
+		Short: "Generate helpers recording which variables an app reads",
+	}
+
+	instrumentCmd.AddCommand(newInstrumentPHPCmd())
+
+	return instrumentCmd
+}
+
+// newInstrumentPHPCmd returns the instrument php command.
+func newInstrumentPHPCmd() *cobra.Command {
+
+	instrumentPHPCmd := &cobra.Command{
+		Use:   "php",
+		Short: "Generate a PHP helper logging the env keys a Laravel app reads",
+		Long: `Php writes a helper that wraps Laravel's env() function and records the
+keys read at runtime to storage/logs/loadenv-env-usage.json. Use the file
+with loadenv unused --from-runtime to find dead variables.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := instrumentPHP(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	instrumentPHPCmd.Flags().StringVarP(&flags.instrumentOutput, "output", "o", "bootstrap/loadenv-instrument.php", "file to write the helper to")
+
+	return instrumentPHPCmd
+}
+
+// phpInstrument defines env() before Laravel's helpers do, which only
+// define it when it does not exist yet, so it has to be required before
+// vendor/autoload.php. Reads through getenv() or $_ENV are not recorded.
+const phpInstrument = `<?php
+
+// Generated by loadenv instrument php. Records which environment variables
+// the application reads through env() so that
+// "loadenv unused --from-runtime" can report the ones it never does.
+
+$GLOBALS['__loadenv_keys'] = [];
+
+if (! function_exists('env')) {
+    function env($key, $default = null)
+    {
+        $GLOBALS['__loadenv_keys'][$key] = true;
+
+        return \Illuminate\Support\Env::get($key, $default);
+    }
+}
+
+register_shutdown_function(function () {
+    $file = __DIR__.'/../storage/logs/loadenv-env-usage.json';
+    $keys = array_keys($GLOBALS['__loadenv_keys']);
+
+    if (is_file($file)) {
+        $data = json_decode((string) file_get_contents($file), true);
+        $keys = array_merge($keys, isset($data['keys']) ? $data['keys'] : []);
+    }
+
+    $keys = array_values(array_unique($keys));
+    sort($keys);
+
+    @file_put_contents($file, json_encode(['keys' => $keys], JSON_PRETTY_PRINT), LOCK_EX);
+});
+`
+
+// instrumentPHP writes the PHP helper and explains how to enable it.
+func instrumentPHP() error {
+
+	if err := checkReadOnly("instrument php"); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(flags.instrumentOutput), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(flags.instrumentOutput, []byte(phpInstrument), 0644); err != nil {
+		return err
+	}
+
+	info("Wrote %s\n", flags.instrumentOutput)
+	info("Require it before vendor/autoload.php in public/index.php and artisan, e.g.:\n")
+	info("    require __DIR__.'/../%s';\n", filepath.ToSlash(flags.instrumentOutput))
+
+	return nil
+}
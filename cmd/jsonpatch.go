@@ -0,0 +1,239 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonPatchOp is an operation of an RFC 6902 JSON patch.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeJSON decodes a JSON value, keeping numbers as written so large
+// integers do not lose precision.
+func decodeJSON(s string) (interface{}, error) {
+
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+
+	return v, nil
+}
+
+// encodeJSON encodes v on a single line, or indented by two spaces.
+func encodeJSON(v interface{}, indent bool) (string, error) {
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// applyJSONPatch applies the add, remove, replace and test operations of
+// patch, a JSON patch document, to the JSON value doc.
+func applyJSONPatch(doc, patch string) (string, error) {
+
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return "", err
+	}
+
+	var ops []jsonPatchOp
+	if err := json.Unmarshal([]byte(patch), &ops); err != nil {
+		return "", fmt.Errorf("can not parse the patch, it must be a JSON array of operations: %v", err)
+	}
+
+	for _, op := range ops {
+		tokens, err := jsonPointer(op.Path)
+		if err != nil {
+			return "", err
+		}
+
+		var value interface{}
+		switch op.Op {
+		case "add", "replace", "test":
+			if len(op.Value) == 0 {
+				return "", fmt.Errorf("can not %s %s without a value", op.Op, op.Path)
+			}
+			if value, err = decodeJSON(string(op.Value)); err != nil {
+				return "", err
+			}
+		case "remove":
+		default:
+			return "", fmt.Errorf("can not apply %q at %s, use add, remove, replace or test", op.Op, op.Path)
+		}
+
+		if op.Op == "test" {
+			current, err := jsonAt(v, tokens)
+			if err != nil {
+				return "", err
+			}
+			if !reflect.DeepEqual(current, value) {
+				got, _ := encodeJSON(current, false)
+				return "", fmt.Errorf("the test of %s failed, the value is %s", op.Path, got)
+			}
+			continue
+		}
+
+		if v, err = patchJSON(v, tokens, op.Op, value); err != nil {
+			return "", fmt.Errorf("can not %s %s: %v", op.Op, op.Path, err)
+		}
+	}
+
+	return encodeJSON(v, false)
+}
+
+// jsonPointer splits an RFC 6901 JSON pointer, e.g. /a/0/b, into its
+// unescaped reference tokens.
+func jsonPointer(path string) ([]string, error) {
+
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("can not use %q as a path, it must start with /", path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// jsonIndex returns the array index token refers to in an array of n
+// elements. With end, n itself and "-" for past the last one are allowed.
+func jsonIndex(token string, n int, end bool) (int, error) {
+
+	if token == "-" && end {
+		return n, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > n || (i == n && !end) || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%q is not an index of the array of %d element(s)", token, n)
+	}
+
+	return i, nil
+}
+
+// jsonAt returns the value at tokens in v.
+func jsonAt(v interface{}, tokens []string) (interface{}, error) {
+
+	for _, token := range tokens {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("the object has no member %q", token)
+			}
+			v = child
+		case []interface{}:
+			i, err := jsonIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("can not look up %q in a value that is not an object or array", token)
+		}
+	}
+
+	return v, nil
+}
+
+// patchJSON applies op with value at tokens in v and returns the patched
+// value.
+func patchJSON(v interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+
+	if len(tokens) == 0 {
+		if op == "remove" {
+			return nil, fmt.Errorf("the whole value can not be removed")
+		}
+		return value, nil
+	}
+
+	token, last := tokens[0], len(tokens) == 1
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok && (!last || op != "add") {
+			return nil, fmt.Errorf("the object has no member %q", token)
+		}
+		if !last {
+			child, err := patchJSON(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			node[token] = child
+			return node, nil
+		}
+		if op == "remove" {
+			delete(node, token)
+		} else {
+			node[token] = value
+		}
+		return node, nil
+
+	case []interface{}:
+		i, err := jsonIndex(token, len(node), last && op == "add")
+		if err != nil {
+			return nil, err
+		}
+		if !last {
+			child, err := patchJSON(node[i], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = child
+			return node, nil
+		}
+		switch op {
+		case "add":
+			node = append(node[:i], append([]interface{}{value}, node[i:]...)...)
+		case "remove":
+			node = append(node[:i], node[i+1:]...)
+		default:
+			node[i] = value
+		}
+		return node, nil
+	}
+
+	return nil, fmt.Errorf("can not look up %q in a value that is not an object or array", token)
+}
@@ -0,0 +1,101 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+// Labels applied to the containers, networks and volumes loadenv creates,
+// so they can be discovered without guessing by name.
+const (
+	projectLabel = "io.loadenv.project"
+	profileLabel = "io.loadenv.profile"
+	envHashLabel = "io.loadenv.env-hash"
+	versionLabel = "io.loadenv.version"
+)
+
+// stackProject returns the compose project name of the stack.
+func stackProject() string {
+
+	if composeProject != "" {
+		return composeProject
+	}
+
+	return projectName()
+}
+
+// projectFilter returns the docker --filter value selecting the resources
+// loadenv created for the current project.
+func projectFilter() string {
+	return "label=" + projectLabel + "=" + stackProject()
+}
+
+// setupLabels labels every service, network and volume of the stack with
+// the project, the profile, i.e. the environment selected with --env, the
+// loadenv version and the hash of the loaded environment. External
+// networks and volumes are not the stack's, so they are left alone.
+func setupLabels() error {
+
+	h, err := loadedEnvHash()
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{
+		projectLabel: stackProject(),
+		profileLabel: selectedEnv(),
+		envHashLabel: h,
+		versionLabel: Version,
+	}
+
+	services := make(map[string]interface{})
+	for _, name := range stackServices() {
+		services[name] = map[string]interface{}{"labels": labels}
+	}
+
+	// the default network is created by compose for every project, unless
+	// it is declared external
+	networks := map[string]interface{}{
+		"default": map[string]interface{}{"labels": labels},
+	}
+	volumes := make(map[string]interface{})
+
+	for _, fname := range append(composeFiles(), overrideFiles...) {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			continue
+		}
+		for name, r := range c.Networks {
+			if _, external := r.externalName(name); !external {
+				networks[name] = map[string]interface{}{"labels": labels}
+			} else {
+				delete(networks, name)
+			}
+		}
+		for name, r := range c.Volumes {
+			if _, external := r.externalName(name); !external {
+				volumes[name] = map[string]interface{}{"labels": labels}
+			}
+		}
+	}
+
+	override := map[string]interface{}{
+		"services": services,
+	}
+	if len(networks) > 0 {
+		override["networks"] = networks
+	}
+	if len(volumes) > 0 {
+		override["volumes"] = volumes
+	}
+
+	return writeOverride("docker-compose.labels.yml", override)
+}
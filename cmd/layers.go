@@ -0,0 +1,216 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// selectedEnv returns the environment chosen with --env or the env
+// config key, or "" when none was chosen.
+func selectedEnv() string {
+
+	if flags.envName != "" {
+		return flags.envName
+	}
+
+	return viper.GetString("env")
+}
+
+// profileKey returns the config key overriding key for the selected
+// environment, profiles.<env>.<key>, when it is set, and key otherwise.
+func profileKey(key string) string {
+
+	if env := selectedEnv(); env != "" && viper.IsSet("profiles."+env+"."+key) {
+		return "profiles." + env + "." + key
+	}
+
+	return key
+}
+
+// dotenvLayers returns the files the environment is loaded from, lowest
+// precedence first. Without an environment that is fname alone. With
+// --env staging it is, as with Vite and dotenv-flow, followed by any
+// further --dotenv files given after the first:
+//
+//	.env                 shared defaults
+//	.env.local           local overrides of the defaults
+//	.env.staging         settings of the staging environment
+//	.env.staging.local   local overrides for staging
+//
+// fname must exist, the others are skipped when missing.
+func dotenvLayers(fname string) []string {
+
+	layers := []string{fname}
+
+	if env := selectedEnv(); env != "" {
+		for _, layer := range []string{fname + ".local", fname + "." + env, fname + "." + env + ".local"} {
+			if _, err := os.Stat(layer); err == nil {
+				layers = append(layers, layer)
+			}
+		}
+	}
+
+	if fname == dotenvFileName() && len(flags.dotenvFiles) > 1 {
+		layers = append(layers, flags.dotenvFiles[1:]...)
+	}
+
+	return layers
+}
+
+// parseEnvLayers reads the variables of every layer of fname, later layers
+// overriding the values of earlier ones or merging with them. Keys keep
+// the position they first appeared at.
+func parseEnvLayers(fname string) ([]envVar, error) {
+
+	var merged []envVar
+	index := make(map[string]int)
+	earlier := make(map[string]string)
+
+	for _, layer := range dotenvLayers(fname) {
+		vars, err := parseEnvFile(layer)
+		if err != nil {
+			return nil, err
+		}
+		if vars, err = mergeLayer(earlier, vars); err != nil {
+			return nil, err
+		}
+
+		for _, v := range vars {
+			if i, ok := index[v.Key]; ok {
+				merged[i] = v
+				continue
+			}
+			index[v.Key] = len(merged)
+			merged = append(merged, v)
+		}
+	}
+
+	return merged, nil
+}
+
+// mergeStrategies are how a layer can combine its value of a key with the
+// value of the earlier layers, configured per key, e.g.
+//
+//	merge:
+//	  PATH: append
+//	  FEATURES: json
+//
+// override replaces the value, append and prepend join the values with a
+// ":" like PATH, and json deep merges JSON objects.
+var mergeStrategies = []string{"override", "append", "prepend", "json"}
+
+// mergeLayer returns the vars of a layer with their values merged with the
+// values of the earlier layers in earlier, and adds them to earlier. The
+// keys a layer defines more than once are overridden within the layer.
+func mergeLayer(earlier map[string]string, vars []envVar) ([]envVar, error) {
+
+	merged := make([]envVar, len(vars))
+	current := make(map[string]string)
+
+	for i, v := range vars {
+		if old, ok := earlier[v.Key]; ok {
+			value, err := mergeValue(v.Key, old, v.Value)
+			if err != nil {
+				return nil, err
+			}
+			v.Value = value
+		}
+		merged[i] = v
+		current[v.Key] = v.Value
+	}
+
+	for key, value := range current {
+		earlier[key] = value
+	}
+
+	return merged, nil
+}
+
+// mergeValue returns the value of key when a layer sets it to value over
+// old, the value of the earlier layers.
+func mergeValue(key, old, value string) (string, error) {
+
+	strategy := viper.GetString("merge." + key)
+
+	switch strategy {
+	case "", "override":
+		return value, nil
+	case "append", "prepend":
+		if old == "" || value == "" {
+			return old + value, nil
+		}
+		if strategy == "prepend" {
+			return value + ":" + old, nil
+		}
+		return old + ":" + value, nil
+	case "json":
+		merged, err := mergeJSON(old, value)
+		if err != nil {
+			return "", fmt.Errorf("can not merge the values of %s: %v", key, err)
+		}
+		return merged, nil
+	}
+
+	return "", fmt.Errorf("unknown merge strategy %q for %s, use %s", strategy, key, strings.Join(mergeStrategies, ", "))
+}
+
+// mergeJSON deep merges the JSON value b into a. Objects are merged key
+// by key, any other value of b replaces the one of a.
+func mergeJSON(a, b string) (string, error) {
+
+	if strings.TrimSpace(a) == "" {
+		return b, nil
+	}
+
+	var av, bv interface{}
+	if err := json.Unmarshal([]byte(a), &av); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal([]byte(b), &bv); err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(deepMerge(av, bv)); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// deepMerge merges b into a when both are JSON objects, and returns b
+// otherwise.
+func deepMerge(a, b interface{}) interface{} {
+
+	am, ok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !ok || !bok {
+		return b
+	}
+
+	for key, value := range bm {
+		am[key] = deepMerge(am[key], value)
+	}
+
+	return am
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// linuxMaxArgStrlen is MAX_ARG_STRLEN, the longest single NAME=value
+	// string exec accepts on linux.
+	linuxMaxArgStrlen = 128 * 1024
+	// linuxArgMax is the usual ARG_MAX, shared by arguments and environment.
+	linuxArgMax = 2 * 1024 * 1024
+	// windowsEnvBlock is the size of the environment block in characters.
+	windowsEnvBlock = 32767
+)
+
+func init() {
+	viper.SetDefault("limits.value_size", 32*1024)
+}
+
+// checkLimits warns when the environment is about to exceed what the
+// platform can pass to docker-compose, or a value is larger than
+// limits.value_size. Failing execs or truncated values are hard to trace
+// back to the environment otherwise.
+func checkLimits() {
+
+	maxValue := viper.GetInt("limits.value_size")
+	total := 0
+
+	for _, kv := range os.Environ() {
+		total += len(kv) + 1
+
+		key := strings.SplitN(kv, "=", 2)[0]
+		size := len(kv) - len(key) - 1
+
+		if maxValue > 0 && size > maxValue {
+			warn("%s is %d bytes, more than limits.value_size (%d)\n", key, size, maxValue)
+		}
+		if runtime.GOOS == "linux" && len(kv) >= linuxMaxArgStrlen {
+			warn("%s is longer than the %d bytes linux allows for a single variable, starting docker-compose will fail\n", key, linuxMaxArgStrlen)
+		}
+	}
+
+	limit, platform := linuxArgMax, "the usual ARG_MAX"
+	if runtime.GOOS == "windows" {
+		limit, platform = windowsEnvBlock, "the windows environment block"
+	}
+	if viper.IsSet("limits.total_size") {
+		limit, platform = viper.GetInt("limits.total_size"), "limits.total_size"
+	}
+
+	if total > limit {
+		warn("the environment is %d bytes, more than %s (%d)\n", total, platform, limit)
+	} else if total > limit*9/10 {
+		warn("the environment is %d bytes, close to %s (%d)\n", total, platform, limit)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// NewLintCmd returns the lint command and its subcommands.
+func NewLintCmd() *cobra.Command {
+
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Lint project files for environment related problems",
+	}
+
+	lintCmd.AddCommand(newLintComposeCmd())
+
+	return lintCmd
+}
+
+// newLintComposeCmd returns the lint compose command.
+func newLintComposeCmd() *cobra.Command {
+
+	lintComposeCmd := &cobra.Command{
+		Use:   "compose",
+		Short: "Lint the compose file for anti-patterns in env usage",
+		Long: `Lint compose flags plaintext secrets in the compose file, env_file
+entries pointing at missing files, variables referenced without a default
+that the dotenv file does not define, and services missing env_file where
+their siblings have it.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := lintCompose(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return lintComposeCmd
+}
+
+// lintService is the part of a compose service the linter inspects.
+type lintService struct {
+	Environment interface{} `yaml:"environment"`
+	EnvFile     interface{} `yaml:"env_file"`
+}
+
+// defaultRefRe matches ${VAR} references, capturing whether a default or
+// error (:-, -, :?, ?) follows the name.
+var defaultRefRe = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:?[-?][^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// lintCompose lints the project's compose file and returns an error when
+// anything was found.
+func lintCompose() error {
+
+	fname, err := findComposeFile()
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+
+	var raw struct {
+		Services map[string]lintService `yaml:"services"`
+	}
+	var tree struct {
+		Services map[string]interface{} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+	if err := yaml.Unmarshal(b, &tree); err != nil {
+		return fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+
+	defined := make(map[string]bool)
+	if vars, err := parseEnvLayers(dotenvFileName()); err == nil {
+		for _, v := range vars {
+			defined[v.Key] = true
+		}
+	}
+
+	var names []string
+	withEnvFile := 0
+	for name, svc := range raw.Services {
+		names = append(names, name)
+		if svc.EnvFile != nil {
+			withEnvFile++
+		}
+	}
+	sort.Strings(names)
+
+	var issues []string
+	report := func(service, format string, args ...interface{}) {
+		issues = append(issues, fmt.Sprintf("%s: service %s: %s", fname, service, fmt.Sprintf(format, args...)))
+	}
+
+	for _, name := range names {
+		svc := raw.Services[name]
+
+		env := environmentMap(svc.Environment)
+		var keys []string
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if isSecret(key) && env[key] != "" && !strings.Contains(env[key], "$") {
+				report(name, "%s is hardcoded in plaintext, reference a variable instead", key)
+			}
+		}
+
+		for _, envFile := range stringList(svc.EnvFile) {
+			if _, err := os.Stat(envFile); os.IsNotExist(err) {
+				report(name, "env_file %s does not exist", envFile)
+			}
+		}
+
+		if svc.EnvFile == nil && withEnvFile > 0 {
+			report(name, "has no env_file while %d other service(s) do", withEnvFile)
+		}
+
+		seen := make(map[string]bool)
+		walkStrings(tree.Services[name], func(s string) {
+			for _, m := range defaultRefRe.FindAllStringSubmatch(s, -1) {
+				ref := m[1] + m[3]
+				if ref == "" || m[2] != "" || defined[ref] || seen[ref] {
+					continue
+				}
+				seen[ref] = true
+				if _, ok := os.LookupEnv(ref); !ok {
+					report(name, "${%s} has no default and is not set in %s", ref, dotenvFileName())
+				}
+			}
+		})
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("lint found %d issue(s)", len(issues))
+	}
+
+	return nil
+}
+
+// environmentMap normalises the list and map forms of a compose
+// environment section into a map.
+func environmentMap(v interface{}) map[string]string {
+
+	env := make(map[string]string)
+
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			kv := strings.SplitN(fmt.Sprint(e), "=", 2)
+			if len(kv) == 2 {
+				env[kv[0]] = kv[1]
+			} else {
+				env[kv[0]] = ""
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			if e == nil {
+				env[fmt.Sprint(k)] = ""
+			} else {
+				env[fmt.Sprint(k)] = fmt.Sprint(e)
+			}
+		}
+	}
+
+	return env
+}
+
+// stringList normalises a compose field that may be a string or a list.
+func stringList(v interface{}) []string {
+
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, e := range v {
+			list = append(list, fmt.Sprint(e))
+		}
+		return list
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("loadtest.profile", "loadtest")
+	viper.SetDefault("loadtest.env", map[string]string{
+		"APP_ENV":          "production",
+		"APP_DEBUG":        "false",
+		"LOG_LEVEL":        "warning",
+		"CACHE_DRIVER":     "redis",
+		"SESSION_DRIVER":   "redis",
+		"QUEUE_CONNECTION": "redis",
+	})
+}
+
+// loadtestLimits are the production sizes of a service, configured under
+// loadtest.resources.<service>.
+type loadtestLimits struct {
+	CPUs   string `mapstructure:"cpus"`
+	Memory string `mapstructure:"memory"`
+}
+
+// NewLoadtestCmd returns the loadtest command and its subcommands.
+func NewLoadtestCmd() *cobra.Command {
+
+	loadtestCmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Load test the project in a production-like stack",
+	}
+
+	prepareCmd := &cobra.Command{
+		Use:   "prepare",
+		Short: "Start a production-like stack and run the load test against it",
+		Long: `Prepare writes the loadtest.env variables, by default with debugging off,
+redis for the cache, sessions and queue and APP_ENV=production, to
+.env.<loadtest.profile> so they are layered over the dotenv file like
+-e does, without copying any of its values. It then starts the stack
+detached with --perf prod-like and the cpus and memory limits of
+loadtest.resources, e.g.
+
+  loadtest:
+    resources:
+      app: {cpus: "2", memory: 1g}
+      mysql: {cpus: "1", memory: 2g}
+    command: k6 run tests/load.js
+
+and finally runs loadtest.command with the resolved environment. The
+command comes from the project config, so the config has to be trusted
+with loadenv allow first.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := prepareLoadtest(); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exit(exitErr.ExitCode())
+				}
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	prepareCmd.Flags().BoolVar(&flags.upBuild, "build", false, "build the images before starting the stack")
+	prepareCmd.Flags().BoolVar(&flags.upNoBuild, "no-build", false, "start the stack without building the images")
+
+	loadtestCmd.AddCommand(prepareCmd)
+
+	return loadtestCmd
+}
+
+// prepareLoadtest writes the profile, starts the stack and runs the load
+// test command.
+func prepareLoadtest() error {
+
+	if err := checkReadOnly("loadtest prepare"); err != nil {
+		return err
+	}
+
+	command := viper.GetString("loadtest.command")
+	if command != "" {
+		if err := checkAllowed("loadtest.command"); err != nil {
+			return err
+		}
+	}
+
+	profile := viper.GetString("loadtest.profile")
+	if err := writeLoadtestProfile(profile); err != nil {
+		return err
+	}
+
+	if err := setupLoadtestLimits(); err != nil {
+		return err
+	}
+
+	flags.envName = profile
+	flags.perfMode = "prod-like"
+	flags.upDetach = true
+	if err := load(); err != nil {
+		return err
+	}
+
+	if command == "" {
+		info("The stack is up, set loadtest.command to run the load test too\n")
+		return nil
+	}
+
+	info("Running %s\n", command)
+
+	c := exec.Command("sh", "-c", command)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return timePhase("run", c.Run)
+}
+
+// writeLoadtestProfile writes the loadtest.env variables to the dotenv
+// layer of profile. Viper lower cases config keys, so the variable names
+// are upper cased.
+func writeLoadtestProfile(profile string) error {
+
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	env := viper.GetStringMapString("loadtest.env")
+	var keys []string
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "# generated by loadenv loadtest prepare, layered over "+flags.defaultDotenv)
+	for _, key := range keys {
+		line, err := d.Format(envVar{Key: strings.ToUpper(key), Value: env[key]}, false)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(&out, line)
+	}
+
+	fname := flags.defaultDotenv + "." + profile
+	tmp := fname + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, fname)
+}
+
+// setupLoadtestLimits writes the override limiting the services to the
+// sizes of loadtest.resources. Version 3 compose files only take limits
+// under deploy.
+func setupLoadtestLimits() error {
+
+	var limits map[string]loadtestLimits
+	if err := viper.UnmarshalKey("loadtest.resources", &limits); err != nil {
+		return err
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	v3 := false
+	if fname, err := findComposeFile(); err == nil {
+		if c, err := readComposeFile(fname); err == nil {
+			v3 = strings.HasPrefix(c.Version, "3")
+		}
+	}
+
+	services := make(map[string]interface{})
+	for name, l := range limits {
+		var cpus float64
+		if l.CPUs != "" {
+			var err error
+			if cpus, err = strconv.ParseFloat(l.CPUs, 64); err != nil {
+				return fmt.Errorf("loadtest.resources.%s.cpus: %q is not a number", name, l.CPUs)
+			}
+		}
+
+		service := make(map[string]interface{})
+		if v3 {
+			limit := make(map[string]string)
+			if l.CPUs != "" {
+				limit["cpus"] = l.CPUs
+			}
+			if l.Memory != "" {
+				limit["memory"] = l.Memory
+			}
+			service["deploy"] = map[string]interface{}{
+				"resources": map[string]interface{}{"limits": limit},
+			}
+		} else {
+			if l.CPUs != "" {
+				service["cpus"] = cpus
+			}
+			if l.Memory != "" {
+				service["mem_limit"] = l.Memory
+			}
+		}
+		services[name] = service
+	}
+
+	return writeOverride("docker-compose.loadtest.yml", map[string]interface{}{
+		"services": services,
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewLogsCmd returns the logs command.
+func NewLogsCmd() *cobra.Command {
+
+	logsCmd := &cobra.Command{
+		Use:   "logs [service...]",
+		Short: "Show the logs of the stack's containers",
+		Long: `Logs prints the output of the stack's containers, or of the given
+services, each line prefixed with its service name in a color of its own,
+e.g. loadenv logs app -f --tail 100.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := logs(args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	logsCmd.Flags().BoolVarP(&flags.logsFollow, "follow", "f", false, "keep printing new output")
+	logsCmd.Flags().StringVar(&flags.logsTail, "tail", "all", "number of lines to show from the end of each log")
+	logsCmd.Flags().StringVar(&flags.logsSince, "since", "", "only show output since a timestamp or a duration ago, e.g. 2025-01-02T13:23:37 or 10m")
+	logsCmd.Flags().BoolVarP(&flags.logsTimestamps, "timestamps", "t", false, "show timestamps")
+	logsCmd.Flags().BoolVar(&flags.logsNoColor, "no-color", false, "do not color the service prefixes")
+
+	return logsCmd
+}
+
+// logs runs docker-compose logs for services of the recorded stack.
+func logs(services []string) error {
+
+	st, err := attachState()
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return fmt.Errorf("no stack started by loadenv in the local directory")
+	}
+
+	for _, name := range services {
+		if len(st.Services) > 0 && !hasString(st.Services, name) {
+			return fmt.Errorf("the stack has no service %s", name)
+		}
+	}
+
+	args := []string{"logs", "--tail", flags.logsTail}
+	if flags.logsFollow {
+		args = append(args, "--follow")
+	}
+	if flags.logsSince != "" {
+		args = append(args, "--since", flags.logsSince)
+	}
+	if flags.logsTimestamps {
+		args = append(args, "--timestamps")
+	}
+	if flags.logsNoColor {
+		args = append(args, "--no-color")
+	}
+
+	c := composeCommand(append(args, services...)...)
+	if flags.logsFollow {
+		return runAttached(c)
+	}
+
+	return runDocker(c)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// NewMatrixCmd returns the matrix command.
+func NewMatrixCmd() *cobra.Command {
+
+	matrixCmd := &cobra.Command{
+		Use:   "matrix [file...]",
+		Short: "Compare the values of every key across env files side by side",
+		Long: `Matrix prints a table with a row per key and a column per env file, by
+default every env file in the local directory. The first column marks
+keys missing from some files with ! and keys whose values differ with *.
+
+Secret values are masked unless --reveal is given, but still compared.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := matrix(args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	matrixCmd.Flags().BoolVar(&flags.matrixReveal, "reveal", false, "show secret values")
+	matrixCmd.Flags().BoolVar(&flags.matrixOnlyDiff, "only-diff", false, "only show keys that differ or are missing somewhere")
+	matrixCmd.Flags().IntVar(&flags.matrixWidth, "width", 30, "truncate values to this many characters, 0 to never truncate")
+
+	return matrixCmd
+}
+
+// matrix prints the table of the keys of files.
+func matrix(files []string) error {
+
+	if len(files) == 0 {
+		var err error
+		if files, err = envFiles(); err != nil {
+			return err
+		}
+	}
+	if len(files) < 2 {
+		return fmt.Errorf("matrix needs at least two env files to compare")
+	}
+
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	values := make([]map[string]string, len(files))
+
+	for i, fname := range files {
+		f, err := os.Open(fname)
+		if err != nil {
+			return err
+		}
+		vars, err := parseEnvDialect(f, fname, d)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		values[i] = make(map[string]string)
+		for _, v := range vars {
+			if !hasKey(values, v.Key) {
+				keys = append(keys, v.Key)
+			}
+			values[i][v.Key] = v.Value
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, " \tKEY\t%s\n", strings.Join(files, "\t"))
+
+	for _, key := range keys {
+		mark := " "
+		cells := make([]string, len(files))
+		first, firstOK := values[0][key]
+
+		for i := range files {
+			value, ok := values[i][key]
+			if !ok {
+				mark = "!"
+				cells[i] = "-"
+				continue
+			}
+			if mark == " " && (!firstOK || value != first) {
+				mark = "*"
+			}
+			cells[i] = matrixCell(key, value)
+		}
+
+		if flags.matrixOnlyDiff && mark == " " {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", mark, key, strings.Join(cells, "\t"))
+	}
+
+	return w.Flush()
+}
+
+// hasKey reports whether any of the value maps defines key.
+func hasKey(values []map[string]string, key string) bool {
+
+	for _, m := range values {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matrixCell returns how value is shown in the table.
+func matrixCell(key, value string) string {
+
+	if value == "" {
+		return `""`
+	}
+	if isSecret(key) && !flags.matrixReveal {
+		return "********"
+	}
+
+	value = strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(value)
+	if r := []rune(value); flags.matrixWidth > 0 && len(r) > flags.matrixWidth {
+		value = string(r[:flags.matrixWidth-1]) + "…"
+	}
+
+	return value
+}
@@ -0,0 +1,285 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// mocksFile lists the mocks enabled in the project.
+const mocksFile = ".loadenv/mocks.json"
+
+func init() {
+	viper.SetDefault("mock.image", "wiremock/wiremock:3.3.1")
+	viper.SetDefault("mock.port", 8080)
+}
+
+// mockConfig configures a mocked API.
+type mockConfig struct {
+	Hosts []string          `mapstructure:"hosts"`
+	Env   map[string]string `mapstructure:"env"`
+	Dir   string            `mapstructure:"dir"`
+}
+
+// NewMockCmd returns the mock command and its subcommands.
+func NewMockCmd() *cobra.Command {
+
+	mockCmd := &cobra.Command{
+		Use:   "mock",
+		Short: "Serve canned responses for external APIs",
+		Long: `Mock replaces the external APIs configured under mock.services with a
+wiremock container serving the stubs in mocks/<name>. The container answers
+for the configured hosts inside the stack, and *_URL variables pointing at
+them are rewritten to it. Mocks apply the next time the stack starts.`,
+	}
+
+	mockCmd.AddCommand(
+		newMockToggleCmd("enable", "Enable mocks for the next start of the stack", true),
+		newMockToggleCmd("disable", "Disable mocks for the next start of the stack", false),
+		newMockListCmd(),
+	)
+
+	return mockCmd
+}
+
+// newMockToggleCmd returns the mock enable or disable command.
+func newMockToggleCmd(use, short string, enable bool) *cobra.Command {
+
+	return &cobra.Command{
+		Use:   use + " <name>...",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := toggleMocks(args, enable); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+}
+
+// newMockListCmd returns the mock list command.
+func newMockListCmd() *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the configured mocks",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listMocks(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+}
+
+// mockConfigs returns the mocks configured under mock.services.
+func mockConfigs() (map[string]mockConfig, error) {
+
+	var configs map[string]mockConfig
+	if err := viper.UnmarshalKey("mock.services", &configs); err != nil {
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+// enabledMocks returns the names of the enabled mocks.
+func enabledMocks() ([]string, error) {
+
+	b, err := os.ReadFile(mocksFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", mocksFile, err)
+	}
+
+	return names, nil
+}
+
+// toggleMocks enables or disables the named mocks.
+func toggleMocks(names []string, enable bool) error {
+
+	action := "mock disable"
+	if enable {
+		action = "mock enable"
+	}
+	if err := checkReadOnly(action); err != nil {
+		return err
+	}
+
+	configs, err := mockConfigs()
+	if err != nil {
+		return err
+	}
+
+	enabled, err := enabledMocks()
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	for _, name := range enabled {
+		set[name] = true
+	}
+
+	for _, name := range names {
+		if _, ok := configs[name]; !ok && enable {
+			return fmt.Errorf("no mock %s configured under mock.services", name)
+		}
+		set[name] = enable
+	}
+
+	enabled = nil
+	for name, on := range set {
+		if on {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+
+	b, err := json.Marshal(enabled)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(mocksFile), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(mocksFile, b, 0644); err != nil {
+		return err
+	}
+
+	if len(enabled) == 0 {
+		info("No mocks enabled, restart the stack to apply\n")
+	} else {
+		info("Enabled mocks: %s, restart the stack to apply\n", strings.Join(enabled, ", "))
+	}
+
+	return nil
+}
+
+// listMocks prints the configured mocks and whether they are enabled.
+func listMocks() error {
+
+	configs, err := mockConfigs()
+	if err != nil {
+		return err
+	}
+
+	enabled, err := enabledMocks()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		state := "disabled"
+		if hasString(enabled, name) {
+			state = "enabled"
+		}
+		fmt.Printf("%-20s %-9s %s\n", name, state, strings.Join(configs[name].Hosts, ", "))
+	}
+
+	return nil
+}
+
+// setupMocks adds a wiremock service for every enabled mock, aliased to
+// the mocked hosts on the stack's network, and points the app service's
+// variables at it.
+func setupMocks() error {
+
+	enabled, err := enabledMocks()
+	if err != nil || len(enabled) == 0 {
+		return err
+	}
+
+	configs, err := mockConfigs()
+	if err != nil {
+		return err
+	}
+
+	port := viper.GetInt("mock.port")
+	services := make(map[string]interface{})
+	env := make(map[string]string)
+
+	for _, name := range enabled {
+		cfg, ok := configs[name]
+		if !ok {
+			return fmt.Errorf("mock %s is enabled but not configured under mock.services", name)
+		}
+
+		dir := cfg.Dir
+		if dir == "" {
+			dir = filepath.Join("mocks", name)
+		}
+
+		service := "mock-" + name
+		services[service] = map[string]interface{}{
+			"image":   viper.GetString("mock.image"),
+			"command": []string{"--port", fmt.Sprint(port)},
+			"volumes": []string{"./" + filepath.ToSlash(dir) + ":/home/wiremock"},
+			"networks": map[string]interface{}{
+				"default": map[string]interface{}{"aliases": cfg.Hosts},
+			},
+		}
+
+		base := fmt.Sprintf("http://%s:%d", service, port)
+		for _, kv := range os.Environ() {
+			parts := strings.SplitN(kv, "=", 2)
+			if !strings.HasSuffix(parts[0], "_URL") {
+				continue
+			}
+			u, err := url.Parse(parts[1])
+			if err != nil || !hasString(cfg.Hosts, u.Hostname()) {
+				continue
+			}
+			rest := u.EscapedPath()
+			if u.RawQuery != "" {
+				rest += "?" + u.RawQuery
+			}
+			env[parts[0]] = base + rest
+		}
+		for k, v := range cfg.Env {
+			// viper lower cases config keys
+			env[strings.ToUpper(k)] = v
+		}
+	}
+
+	if len(env) > 0 {
+		services[viper.GetString("app_service")] = map[string]interface{}{"environment": env}
+	}
+
+	return writeOverride("docker-compose.mock.yml", map[string]interface{}{
+		"services": services,
+	})
+}
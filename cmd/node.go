@@ -0,0 +1,147 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	frontendVite = "vite"
+	frontendMix  = "mix"
+)
+
+func init() {
+	viper.SetDefault("app_service", "app")
+	viper.SetDefault("node.image", "node:lts-alpine")
+	viper.SetDefault("node.workdir", "/var/www/html")
+}
+
+// detectFrontend reports which asset bundler the project in the current
+// directory uses, or an empty string when none is found.
+func detectFrontend() string {
+
+	for _, fname := range []string{"vite.config.js", "vite.config.ts", "vite.config.mjs"} {
+		if _, err := os.Stat(fname); err == nil {
+			return frontendVite
+		}
+	}
+
+	if _, err := os.Stat("webpack.mix.js"); err == nil {
+		return frontendMix
+	}
+
+	return ""
+}
+
+// setupNode adds a node service running the asset dev server to the stack
+// and passes the VITE_* variables to both it and the app service.
+func setupNode() error {
+
+	frontend := detectFrontend()
+	if frontend == "" {
+		return fmt.Errorf("can not find vite.config.js or webpack.mix.js in the local directory")
+	}
+
+	// The dev server runs inside a container, so HMR clients in the
+	// browser have to be pointed at the port published on the host.
+	setDefaultEnv("VITE_HMR_HOST", "localhost")
+	setDefaultEnv("VITE_HMR_PORT", "5173")
+
+	env := make(map[string]string)
+	for _, key := range viteKeys() {
+		env[key] = "${" + key + "}"
+	}
+
+	command := "npm run dev -- --host 0.0.0.0"
+	if frontend == frontendMix {
+		command = "npm run hot"
+	}
+	if viper.IsSet("node.command") {
+		command = viper.GetString("node.command")
+	}
+
+	workdir := viper.GetString("node.workdir")
+	port := os.Getenv("VITE_HMR_PORT")
+
+	services := map[string]interface{}{
+		"node": map[string]interface{}{
+			"image":       viper.GetString("node.image"),
+			"working_dir": workdir,
+			"volumes":     []string{".:" + workdir},
+			"command":     command,
+			"ports":       []string{port + ":" + port},
+			"environment": env,
+		},
+	}
+
+	if app, err := hasService(viper.GetString("app_service")); err != nil {
+		return err
+	} else if app {
+		services[viper.GetString("app_service")] = map[string]interface{}{
+			"environment": env,
+		}
+	}
+
+	return writeOverride("docker-compose.node.yml", map[string]interface{}{
+		"services": services,
+	})
+}
+
+// hasService reports whether the project's compose file defines name.
+func hasService(name string) (bool, error) {
+
+	fname, err := findComposeFile()
+	if err != nil {
+		return false, err
+	}
+
+	c, err := readComposeFile(fname)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := c.Services[name]
+
+	return ok, nil
+}
+
+// viteKeys returns the sorted names of all VITE_* variables in the environment.
+func viteKeys() []string {
+
+	var keys []string
+
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "VITE_") {
+			keys = append(keys, strings.SplitN(kv, "=", 2)[0])
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// setDefaultEnv sets key to value unless it is already set.
+func setDefaultEnv(key, value string) {
+
+	if _, ok := os.LookupEnv(key); !ok {
+		setEnv(key, value)
+	}
+}
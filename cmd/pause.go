@@ -0,0 +1,72 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewPauseCmd returns the pause command.
+func NewPauseCmd() *cobra.Command {
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause [service...]",
+		Short: "Pause the stack's containers",
+		Long: `Pause freezes the processes of the stack's containers, or of the given
+services, so they stop using CPU while keeping their memory and state.
+Continue them with loadenv resume.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := pauseStack("pause", args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return pauseCmd
+}
+
+// NewResumeCmd returns the resume command.
+func NewResumeCmd() *cobra.Command {
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume [service...]",
+		Short: "Resume the stack's paused containers",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := pauseStack("unpause", args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return resumeCmd
+}
+
+// pauseStack runs docker-compose pause or unpause for the recorded stack.
+func pauseStack(action string, services []string) error {
+
+	st, err := attachState()
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return fmt.Errorf("no stack started by loadenv in the local directory")
+	}
+
+	return runDocker(composeCommand(append([]string{action}, services...)...))
+}
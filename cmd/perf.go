@@ -0,0 +1,119 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("php.ini_dir", "/usr/local/etc/php/loadenv.d")
+}
+
+// perfProfile is a set of php.ini settings and environment variables for
+// the app service.
+type perfProfile struct {
+	ini [][2]string
+	env map[string]string
+}
+
+// devIni are the php.ini settings of the dev and debug modes, which see
+// changes to the code right away.
+var devIni = [][2]string{
+	{"opcache.enable", "1"},
+	{"opcache.validate_timestamps", "1"},
+	{"opcache.revalidate_freq", "0"},
+	{"realpath_cache_size", "4096K"},
+	{"realpath_cache_ttl", "120"},
+}
+
+// perfProfiles are the modes accepted by --perf. Xdebug slows every
+// request down, so only debug and profile turn it on.
+var perfProfiles = map[string]perfProfile{
+	"dev": {
+		ini: devIni,
+		env: map[string]string{"XDEBUG_MODE": "off"},
+	},
+	"debug": {
+		ini: devIni,
+		env: map[string]string{"XDEBUG_MODE": "develop,debug"},
+	},
+	"profile": {
+		ini: [][2]string{
+			{"opcache.enable", "1"},
+			{"opcache.validate_timestamps", "1"},
+			{"opcache.revalidate_freq", "2"},
+			{"realpath_cache_size", "4096K"},
+			{"realpath_cache_ttl", "600"},
+		},
+		env: map[string]string{"XDEBUG_MODE": "profile"},
+	},
+	"prod-like": {
+		ini: [][2]string{
+			{"opcache.enable", "1"},
+			{"opcache.validate_timestamps", "0"},
+			{"opcache.memory_consumption", "256"},
+			{"opcache.max_accelerated_files", "20000"},
+			{"realpath_cache_size", "4096K"},
+			{"realpath_cache_ttl", "600"},
+		},
+		env: map[string]string{"XDEBUG_MODE": "off"},
+	},
+}
+
+// setupPerf generates the php.ini overrides for the given mode and mounts
+// them into the app service. PHP_INI_SCAN_DIR with a leading separator
+// appends the directory to the ones compiled into the image.
+func setupPerf(mode string) error {
+
+	profile, ok := perfProfiles[mode]
+	if !ok {
+		return fmt.Errorf("unknown --perf mode %q, use dev, debug, profile or prod-like", mode)
+	}
+
+	var ini bytes.Buffer
+	fmt.Fprintf(&ini, "; generated by loadenv --perf %s\n", mode)
+	for _, kv := range profile.ini {
+		fmt.Fprintf(&ini, "%s=%s\n", kv[0], kv[1])
+	}
+
+	dir := filepath.Join(tmpDir, "php")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "zz-loadenv-perf.ini"), ini.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	iniDir := viper.GetString("php.ini_dir")
+
+	env := map[string]string{"PHP_INI_SCAN_DIR": ":" + iniDir}
+	for k, v := range profile.env {
+		env[k] = v
+	}
+
+	return writeOverride("docker-compose.perf.yml", map[string]interface{}{
+		"services": map[string]interface{}{
+			viper.GetString("app_service"): map[string]interface{}{
+				"environment": env,
+				"volumes":     []string{"./" + dir + ":" + iniDir + ":ro"},
+			},
+		},
+	})
+}
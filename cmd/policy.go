@@ -0,0 +1,133 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func init() {
+	viper.SetDefault("policy.query", "data.loadenv.deny")
+}
+
+// policyInput is the document policies are evaluated against.
+type policyInput struct {
+	Env     map[string]string `json:"env"`
+	Compose interface{}       `json:"compose"`
+}
+
+// checkPolicies evaluates the rego policies listed under policy.files in
+// the config against the variables in fname and the compose file, using
+// the opa binary. It returns an error listing the violations, if any.
+func checkPolicies(fname string) error {
+
+	files := viper.GetStringSlice("policy.files")
+	if len(files) == 0 {
+		return nil
+	}
+
+	// policies see every value and can send them elsewhere with http.send
+	if err := checkAllowed("policy.files"); err != nil {
+		return err
+	}
+
+	vars, err := parseEnvLayers(fname)
+	if err != nil {
+		return err
+	}
+
+	input := policyInput{Env: make(map[string]string)}
+	for _, v := range vars {
+		input.Env[v.Key] = v.Value
+	}
+
+	if compose, err := findComposeFile(); err == nil {
+		b, err := os.ReadFile(compose)
+		if err != nil {
+			return err
+		}
+
+		var raw interface{}
+		if err := yaml.Unmarshal(b, &raw); err != nil {
+			return fmt.Errorf("can not parse %s: %v", compose, err)
+		}
+		input.Compose = jsonCompatible(raw)
+	}
+
+	b, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"eval", "--format", "raw", "--stdin-input"}
+	for _, fname := range files {
+		args = append(args, "--data", fname)
+	}
+	args = append(args, viper.GetString("policy.query"))
+
+	var stdout bytes.Buffer
+	c := exec.Command("opa", args...)
+	c.Stdin = bytes.NewReader(b)
+	c.Stdout = &stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("can not evaluate policies: %v", err)
+	}
+
+	out := strings.TrimSpace(stdout.String())
+	if out == "" {
+		// the query is undefined, nothing was denied
+		return nil
+	}
+
+	var violations []string
+	if err := json.Unmarshal([]byte(out), &violations); err != nil {
+		return fmt.Errorf("policy query must return a list of messages: %v", err)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("policy violations:\n  - %s", strings.Join(violations, "\n  - "))
+}
+
+// jsonCompatible converts the maps produced by yaml.v2 into maps with
+// string keys so v can be encoded as JSON.
+func jsonCompatible(v interface{}) interface{} {
+
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonCompatible(e)
+		}
+		return v
+	}
+
+	return v
+}
@@ -0,0 +1,200 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewProbeCmd returns the probe command.
+func NewProbeCmd() *cobra.Command {
+
+	probeCmd := &cobra.Command{
+		Use:   "probe <target>",
+		Short: "Wait until a service is ready",
+		Long: `Probe checks a target until it is ready, retrying with backoff. Targets:
+
+  http://host/path, https://host/path   status (and optionally body) matches
+  tcp://host:port                        port accepts connections
+  unix:///path/to.sock                   socket accepts connections
+  mysql://host:port                      server sends its handshake
+  redis://host:port                      server answers PING`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := probeWithRetry(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	probeCmd.Flags().IntVar(&flags.probeStatus, "status", 0, "expected http status (default is any 2xx)")
+	probeCmd.Flags().StringVar(&flags.probeBody, "body", "", "regular expression the http body must match")
+	probeCmd.Flags().IntVar(&flags.probeRetries, "retries", 10, "number of attempts")
+	probeCmd.Flags().DurationVar(&flags.probeInterval, "interval", time.Second, "delay before the first retry, doubled after each attempt")
+	probeCmd.Flags().DurationVar(&flags.probeMaxInterval, "max-interval", 10*time.Second, "maximum delay between attempts")
+	probeCmd.Flags().DurationVar(&flags.probeTimeout, "timeout", 2*time.Second, "timeout of a single attempt")
+
+	return probeCmd
+}
+
+// probeWithRetry probes target until it succeeds or the retries run out.
+func probeWithRetry(target string) error {
+
+	interval := flags.probeInterval
+
+	var err error
+	for attempt := 1; attempt <= flags.probeRetries; attempt++ {
+		if err = probe(target); err == nil {
+			return nil
+		}
+
+		if attempt < flags.probeRetries {
+			time.Sleep(interval)
+			interval *= 2
+			if interval > flags.probeMaxInterval {
+				interval = flags.probeMaxInterval
+			}
+		}
+	}
+
+	return fmt.Errorf("%s is not ready after %d attempt(s): %v", target, flags.probeRetries, err)
+}
+
+// probe checks target once.
+func probe(target string) error {
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return probeHTTP(target)
+	case "tcp":
+		return probeConn("tcp", u.Host, nil)
+	case "unix":
+		return probeConn("unix", u.Path, nil)
+	case "mysql":
+		return probeConn("tcp", u.Host, mysqlHandshake)
+	case "redis":
+		return probeConn("tcp", u.Host, redisPing)
+	}
+
+	return fmt.Errorf("unsupported probe target %s", target)
+}
+
+func probeHTTP(target string) error {
+
+	client := http.Client{Timeout: flags.probeTimeout}
+
+	resp, err := client.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if flags.probeStatus != 0 && resp.StatusCode != flags.probeStatus {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, flags.probeStatus)
+	}
+	if flags.probeStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if flags.probeBody != "" {
+		re, err := regexp.Compile(flags.probeBody)
+		if err != nil {
+			return err
+		}
+
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return err
+		}
+		if !re.Match(b) {
+			return fmt.Errorf("body does not match %q", flags.probeBody)
+		}
+	}
+
+	return nil
+}
+
+// probeConn connects to addr and runs check, if any, on the connection.
+func probeConn(network, addr string, check func(net.Conn) error) error {
+
+	conn, err := net.DialTimeout(network, addr, flags.probeTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if check == nil {
+		return nil
+	}
+
+	conn.SetDeadline(time.Now().Add(flags.probeTimeout))
+
+	return check(conn)
+}
+
+// mysqlHandshake reads the first packet a MySQL server sends and checks
+// it is a protocol 10 handshake rather than an error packet.
+func mysqlHandshake(conn net.Conn) error {
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+
+	switch header[4] {
+	case 0x0a:
+		return nil
+	case 0xff:
+		return fmt.Errorf("mysql server refused the connection")
+	}
+
+	return fmt.Errorf("unexpected mysql handshake")
+}
+
+// redisPing sends PING and accepts PONG, or an authentication error which
+// still means the server is up.
+func redisPing(conn net.Conn) error {
+
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(line, "+PONG") || strings.HasPrefix(line, "-NOAUTH") {
+		return nil
+	}
+
+	return fmt.Errorf("unexpected redis reply %q", strings.TrimSpace(line))
+}
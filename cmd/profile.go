@@ -0,0 +1,324 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// NewProfileCmd returns the profile command and its subcommands.
+func NewProfileCmd() *cobra.Command {
+
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Share the profiles of the config between projects",
+		Long: `A profile is the settings under profiles.<env> in the config, which
+override the top level ones when loadenv runs with --env <env>, e.g. the
+down.prune_images of the staging environment.
+
+Export prints a profile of the project config as YAML, and import writes
+one into it, so a tuned profile can be copied to another project:
+
+  loadenv profile export staging > staging.yaml
+  loadenv profile import staging staging.yaml
+
+With --global they read and write the user config in the home directory
+instead, e.g. to promote a profile of the project to the user's defaults:
+
+  loadenv profile export staging | loadenv profile import staging --global -`,
+	}
+	profileCmd.PersistentFlags().BoolVarP(&flags.profileGlobal, "global", "g", false, "use the user config in the home directory instead of the project config")
+
+	exportCmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: "Print a profile as YAML",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := exportProfile(os.Stdout, args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import <name> [file]",
+		Short: "Write a profile exported as YAML into the config",
+		Long: `Import sets profiles.<name> of the config to the profile in file, or read
+from stdin when file is - or not given, replacing the profile of that
+name. The rest of the config file is kept as it is, comments included.
+Only YAML config files are edited.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			fname := "-"
+			if len(args) > 1 {
+				fname = args[1]
+			}
+			if err := importProfile(args[0], fname); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	profileCmd.AddCommand(exportCmd, importCmd)
+
+	return profileCmd
+}
+
+// profileConfigFile returns the config file the profile commands use, the
+// project's or with --global the user's, and whether it exists. When it
+// does not, it is the YAML file to create.
+func profileConfigFile() (string, bool, error) {
+
+	dir := "."
+	if flags.profileGlobal {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", false, err
+		}
+		dir = home
+	}
+
+	v := viper.New()
+	v.AddConfigPath(dir)
+	v.SetConfigName(flags.configName)
+	if err := v.ReadInConfig(); err == nil {
+		return v.ConfigFileUsed(), true, nil
+	}
+
+	return filepath.Join(dir, flags.configName+".yaml"), false, nil
+}
+
+// exportProfile writes the profile name of the config to w as YAML.
+func exportProfile(w io.Writer, name string) error {
+
+	fname, ok, err := profileConfigFile()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("can not find a %s config file to export from", flags.configName)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(fname)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("can not read %s: %v", fname, err)
+	}
+
+	profile := v.GetStringMap("profiles." + name)
+	if len(profile) == 0 {
+		return fmt.Errorf("%s has no profile %s", fname, name)
+	}
+
+	b, err := yaml.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+
+	return err
+}
+
+// importProfile sets the profile name of the config to the one in the
+// YAML file fname, or stdin when fname is -.
+func importProfile(name, fname string) error {
+
+	if err := checkReadOnly("profile import"); err != nil {
+		return err
+	}
+	if name == "" || strings.ContainsAny(name, ".: \t#") {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+
+	var b []byte
+	var err error
+	if fname == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(fname)
+	}
+	if err != nil {
+		return err
+	}
+
+	var profile yaml.MapSlice
+	if err := yaml.Unmarshal(b, &profile); err != nil {
+		return fmt.Errorf("can not parse the profile: %v", err)
+	}
+	if len(profile) == 0 {
+		return fmt.Errorf("the profile is empty")
+	}
+
+	config, ok, err := profileConfigFile()
+	if err != nil {
+		return err
+	}
+	if ext := filepath.Ext(config); ext != ".yaml" && ext != ".yml" {
+		return fmt.Errorf("can not import into %s, only YAML config files are edited", config)
+	}
+
+	var before []byte
+	mode := os.FileMode(0644)
+	if ok {
+		fi, err := os.Stat(config)
+		if err != nil {
+			return err
+		}
+		mode = fi.Mode().Perm()
+		if before, err = os.ReadFile(config); err != nil {
+			return err
+		}
+	}
+
+	after, err := setProfile(before, name, profile)
+	if err != nil {
+		return fmt.Errorf("can not edit %s: %v", config, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(config), "."+filepath.Base(config)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(after); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// the project config is part of what allow approved
+	if err := keepAllowed(func() error { return os.Rename(tmp.Name(), config) }); err != nil {
+		return err
+	}
+
+	info("imported profile %s into %s\n", name, config)
+
+	return nil
+}
+
+// setProfile returns the YAML config b with profiles.<name> set to
+// profile. Only the lines of that profile are rewritten, so the comments
+// and the layout of the rest of b are kept.
+func setProfile(b []byte, name string, profile yaml.MapSlice) ([]byte, error) {
+
+	out, err := yaml.Marshal(yaml.MapSlice{{Key: name, Value: profile}})
+	if err != nil {
+		return nil, err
+	}
+	block := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+
+	var lines []string
+	if text := strings.TrimRight(string(b), "\n"); text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	start := -1
+	for i, line := range lines {
+		if rest := strings.TrimPrefix(line, "profiles:"); rest != line {
+			if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("profiles must be a block mapping to be edited, not %s", rest)
+			}
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		lines = append(lines, "profiles:")
+		for _, line := range block {
+			lines = append(lines, "  "+line)
+		}
+		return []byte(strings.Join(lines, "\n") + "\n"), nil
+	}
+
+	// the profiles end at the next top level key
+	end, indent := len(lines), ""
+	for i := start + 1; i < len(lines); i++ {
+		if yamlContent(lines[i]) == "" {
+			continue
+		}
+		ws := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+		if ws == "" {
+			end = i
+			break
+		}
+		if indent == "" {
+			indent = ws
+		}
+	}
+	if indent == "" {
+		indent = "  "
+	}
+
+	// replace the profile, or add it after the last one
+	from := start
+	for i := start + 1; i < end; i++ {
+		if strings.HasPrefix(lines[i], indent+name+":") {
+			from = i
+			break
+		}
+	}
+	to := end
+	for j := from + 1; from > start && j < end; j++ {
+		if yamlContent(lines[j]) != "" && len(lines[j])-len(strings.TrimLeft(lines[j], " \t")) <= len(indent) {
+			to = j
+			break
+		}
+	}
+	// blank lines and comments before the next key belong to it
+	for to > from+1 && yamlContent(lines[to-1]) == "" {
+		to--
+	}
+	if from == start {
+		from = to
+	}
+
+	var edited []string
+	edited = append(edited, lines[:from]...)
+	for _, line := range block {
+		edited = append(edited, indent+line)
+	}
+	edited = append(edited, lines[to:]...)
+
+	return []byte(strings.Join(edited, "\n") + "\n"), nil
+}
+
+// yamlContent returns line without its indentation, or "" when it is
+// blank or a comment.
+func yamlContent(line string) string {
+
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+
+	return line
+}
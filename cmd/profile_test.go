@@ -0,0 +1,96 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestSetProfile(t *testing.T) {
+
+	profile := yaml.MapSlice{{Key: "down", Value: yaml.MapSlice{{Key: "prune_images", Value: "project"}}}}
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "no profiles",
+			config: "# the app\napp_service: app\n",
+			want:   "# the app\napp_service: app\nprofiles:\n  staging:\n    down:\n      prune_images: project\n",
+		},
+		{
+			name:   "other profile",
+			config: "profiles:\n    ci:\n        readonly: true\n\n# compose\ncompose:\n  path: .\n",
+			want:   "profiles:\n    ci:\n        readonly: true\n    staging:\n      down:\n        prune_images: project\n\n# compose\ncompose:\n  path: .\n",
+		},
+		{
+			name:   "replaced profile",
+			config: "profiles:\n  staging:\n    up:\n      wait_timeout: 5m\n  # continuous integration\n  ci: {}\n",
+			want:   "profiles:\n  staging:\n    down:\n      prune_images: project\n  # continuous integration\n  ci: {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := setProfile([]byte(tt.config), "staging", profile)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: setProfile =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := setProfile([]byte("profiles: {}\n"), "staging", profile); err == nil {
+		t.Error("setProfile of a flow mapping succeeded, want an error")
+	}
+}
+
+func TestProfileExportImport(t *testing.T) {
+
+	testProject(t)
+
+	config := "# shared settings\nprofiles:\n  staging:\n    down:\n      prune_images: project\n"
+	if err := os.WriteFile(".loadenv.yaml", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var exported bytes.Buffer
+	if err := exportProfile(&exported, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("staging.yaml", exported.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// promote it to the user config, which does not exist yet
+	flags.profileGlobal = true
+	if err := importProfile("staging", "staging.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	var global bytes.Buffer
+	if err := exportProfile(&global, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if global.String() != exported.String() {
+		t.Errorf("exported from the user config:\n%s\nwant\n%s", &global, &exported)
+	}
+}
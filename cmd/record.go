@@ -0,0 +1,638 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewRecordCmd returns the record command.
+func NewRecordCmd() *cobra.Command {
+
+	recordCmd := &cobra.Command{
+		Use:   "record",
+		Short: "Capture what loadenv resolves the environment from",
+		Long: `Record writes a bundle with the dotenv files and their layers, the
+env_file files of the compose services, the config and compose files, the
+flags that affect parsing, the docker versions and hashes of the host
+environment. Secret values are replaced by an HMAC, in the yaml files too
+and wherever else they appear, as are the user and password of URLs, so
+the bundle can be attached to a bug report and replayed with loadenv
+replay.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := record(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	recordCmd.Flags().StringVarP(&flags.recordOutput, "output", "o", "loadenv-record.tgz", "bundle to write")
+
+	return recordCmd
+}
+
+// NewReplayCmd returns the replay command.
+func NewReplayCmd() *cobra.Command {
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <bundle>",
+		Short: "Resolve the environment recorded in a bundle",
+		Long: `Replay resolves the environment from a bundle written by loadenv record
+the way loadenv did on the recording machine, from the recorded files and
+settings only, and prints the result along with what was recorded about
+that machine. Variables the recording machine took from its process
+environment are not set, as their values were not recorded.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := replay(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return replayCmd
+}
+
+// recordManifest describes a recorded bundle.
+type recordManifest struct {
+	RecordedAt     time.Time         `json:"recorded_at"`
+	Version        string            `json:"version"`
+	Platform       string            `json:"platform"`
+	Docker         string            `json:"docker"`
+	Compose        string            `json:"compose"`
+	Dotenv         string            `json:"dotenv"`
+	DotenvFiles    []string          `json:"dotenv_files,omitempty"`
+	Env            string            `json:"env,omitempty"`
+	Dialect        string            `json:"dialect"`
+	Config         string            `json:"config,omitempty"`
+	Files          []string          `json:"files"`
+	HostEnv        map[string]string `json:"host_env"`
+	RedactedValues []string          `json:"redacted_values"`
+}
+
+// hmacKeyRe matches the hash.hmac_key setting, which must not be recorded.
+var hmacKeyRe = regexp.MustCompile(`(?m)^(\s*hmac_key:\s*).*$`)
+
+// record writes the bundle.
+func record() error {
+
+	if err := checkReadOnly("record"); err != nil {
+		return err
+	}
+
+	key, err := hmacKey()
+	if err != nil {
+		return err
+	}
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	m := recordManifest{
+		RecordedAt: time.Now().UTC(),
+		Version:    Version,
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Docker:     commandVersion("docker", "version", "--format", "{{.Server.Version}}"),
+		Compose:    composeVersion(),
+		Dotenv:     bundleName(dotenvFileName()),
+		Env:        selectedEnv(),
+		Dialect:    flags.dialectName,
+		HostEnv:    make(map[string]string),
+	}
+	if m.Dialect == "" {
+		m.Dialect = viper.GetString("dialect")
+	}
+
+	secrets := make(secretValues)
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		m.HostEnv[parts[0]] = redact(key, parts[1])
+		if isSecret(parts[0]) {
+			secrets.add(parts[1])
+		}
+	}
+
+	for _, fname := range flags.dotenvFiles {
+		m.DotenvFiles = append(m.DotenvFiles, bundleName(fname))
+	}
+
+	// the files are read before any is redacted, so the values of secrets
+	// are known wherever they appear
+	var sources []dotenvSource
+	for _, fname := range append(dotenvLayers(dotenvFileName()), localDotenv) {
+		sources = append(sources, dotenvSource{fname, d})
+	}
+	envFiles, err := serviceEnvFiles()
+	if err != nil {
+		return err
+	}
+	// env_file files are written for compose, whose syntax is posix like
+	posix, err := dotenv.LookupDialect("posix")
+	if err != nil {
+		return err
+	}
+	for _, file := range envFiles {
+		sources = append(sources, dotenvSource{file.path, posix})
+	}
+
+	contents := make(map[string][]byte)
+	for i := range sources {
+		src := &sources[i]
+		if _, ok := contents[src.fname]; ok {
+			continue
+		}
+		b, err := os.ReadFile(src.fname)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		contents[src.fname] = b
+		if err := secrets.addDotenv(b, src.fname, src.d); err != nil {
+			return err
+		}
+	}
+
+	yamlFiles := composeFiles()
+	if fname := viper.ConfigFileUsed(); fname != "" {
+		yamlFiles = append(yamlFiles, fname)
+	}
+	// replay applies the schema defaults and computed keys too
+	if _, err := os.Stat(schemaFile); err == nil {
+		yamlFiles = append(yamlFiles, schemaFile)
+	}
+	for _, fname := range yamlFiles {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			return err
+		}
+		contents[fname] = b
+		secrets.addYAML(b)
+	}
+
+	r := secrets.replacer(func(value string) string { return redact(key, value) })
+	files := make(map[string][]byte)
+
+	for _, src := range sources {
+		b, ok := contents[src.fname]
+		if _, done := files[bundleName(src.fname)]; !ok || done {
+			continue
+		}
+		b, redacted, err := redactDotenv(b, src.fname, src.d, key, r)
+		if err != nil {
+			return err
+		}
+		files[bundleName(src.fname)] = b
+		m.RedactedValues = append(m.RedactedValues, redacted...)
+	}
+
+	if fname := viper.ConfigFileUsed(); fname != "" {
+		m.Config = "config" + filepath.Ext(fname)
+		b, redacted := redactYAML(hmacKeyRe.ReplaceAll(contents[fname], []byte("${1}redacted")), m.Config, key, r)
+		files[m.Config] = b
+		m.RedactedValues = append(m.RedactedValues, redacted...)
+	}
+
+	for _, fname := range composeFiles() {
+		b, redacted := redactYAML(contents[fname], bundleName(fname), key, r)
+		files[bundleName(fname)] = b
+		m.RedactedValues = append(m.RedactedValues, redacted...)
+	}
+
+	if b, ok := contents[schemaFile]; ok {
+		b, redacted := redactYAML(b, schemaFile, key, r)
+		files[schemaFile] = b
+		m.RedactedValues = append(m.RedactedValues, redacted...)
+	}
+
+	for name := range files {
+		m.Files = append(m.Files, name)
+	}
+	sort.Strings(m.Files)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, b []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(b)), ModTime: m.RecordedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(b)
+		return err
+	}
+
+	if err := add("manifest.json", manifest); err != nil {
+		return err
+	}
+	for _, name := range m.Files {
+		if err := add("files/"+name, files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(flags.recordOutput, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	info("Wrote %s, %d secret value(s) redacted\n", flags.recordOutput, len(m.RedactedValues))
+
+	return nil
+}
+
+// dotenvSource is a dotenv file to record and the dialect to read it with.
+type dotenvSource struct {
+	fname string
+	d     *dotenv.Dialect
+}
+
+// minSecretLen is the length below which the value of a secret is only
+// redacted as the value of its key, as it is too short to be told apart
+// from other text.
+const minSecretLen = 4
+
+// secretValues are the values of secrets, to be redacted wherever they
+// appear.
+type secretValues map[string]bool
+
+// add adds value, unless it is short or a reference to other variables.
+func (s secretValues) add(value string) {
+
+	if len(value) >= minSecretLen && !strings.Contains(value, "$") {
+		s[value] = true
+	}
+}
+
+// addDotenv adds the values of the secrets of the dotenv file b.
+func (s secretValues) addDotenv(b []byte, fname string, d *dotenv.Dialect) error {
+
+	lines, err := dotenv.ReadLines(bytes.NewReader(b), fname, d)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		if l.Var != nil && isSecret(l.Var.Key) {
+			s.add(l.Var.Value)
+		}
+	}
+
+	return nil
+}
+
+// addYAML adds the values of the secrets set in the yaml b.
+func (s secretValues) addYAML(b []byte) {
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if m := yamlSecretRe.FindStringSubmatch(line); m != nil {
+			if value, _, ok := yamlSecret(m); ok {
+				s.add(value)
+			}
+		}
+	}
+}
+
+// replacer returns a replacer of the values with what replace returns for
+// them, the longest first so a value containing another is replaced as a
+// whole.
+func (s secretValues) replacer(replace func(value string) string) *strings.Replacer {
+
+	var values []string
+	for value := range s {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	var pairs []string
+	for _, value := range values {
+		pairs = append(pairs, value, replace(value))
+	}
+
+	return strings.NewReplacer(pairs...)
+}
+
+// userinfoRe matches the userinfo of a URL, e.g. user:password@, that is
+// not made of references.
+var userinfoRe = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.-]*://)([^/?#@\s"'$]+)@`)
+
+// redactEmbedded replaces the values of secrets replaced by r, and the
+// userinfo of URLs, in text with their HMAC.
+func redactEmbedded(text string, key []byte, r *strings.Replacer) string {
+
+	text = r.Replace(text)
+
+	return userinfoRe.ReplaceAllStringFunc(text, func(u string) string {
+		m := userinfoRe.FindStringSubmatch(u)
+		return m[1] + redact(key, m[2]) + "@"
+	})
+}
+
+// redactDotenv replaces the values of secrets in the dotenv file b with
+// their HMAC, line by line so comments and annotations are kept. A
+// multi-line value is replaced as a whole. On the other lines the values
+// of secrets replaced by r and the userinfo of URLs are replaced.
+func redactDotenv(b []byte, fname string, d *dotenv.Dialect, key []byte, r *strings.Replacer) ([]byte, []string, error) {
+
+	lines, err := dotenv.ReadLines(bytes.NewReader(b), fname, d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out bytes.Buffer
+	var redacted []string
+
+	for _, l := range lines {
+		line := l.Text
+		if l.Var != nil && isSecret(l.Var.Key) {
+			line = l.Text[:l.KeyOffset] + l.Var.Key + "=" + redact(key, l.Var.Value)
+			redacted = append(redacted, l.Var.Key)
+		} else if line = redactEmbedded(l.Text, key, r); line != l.Text {
+			if l.Var != nil {
+				redacted = append(redacted, l.Var.Key)
+			} else {
+				redacted = append(redacted, bundleName(fname))
+			}
+		}
+
+		fmt.Fprintln(&out, line)
+	}
+
+	return out.Bytes(), redacted, nil
+}
+
+// yamlSecretRe matches a yaml line setting a key, KEY: value, or a list
+// item setting a variable, - KEY=value, e.g. in a service's environment.
+var yamlSecretRe = regexp.MustCompile(`^(\s*(?:-\s+)?["']?)([A-Za-z_][A-Za-z0-9_.-]*)(["']?\s*:[ \t]+|=)(.*?)[ \t]*$`)
+
+// yamlSecret returns the value set by the yaml line matched as m, and the
+// quote around a - KEY=value item, when its key is a secret and the value
+// is its own. References to variables, flow collections and block scalars
+// hold no value of their own.
+func yamlSecret(m []string) (string, string, bool) {
+
+	name, value := m[2], m[4]
+
+	// - KEY=value items may be quoted as a whole
+	quote := ""
+	if strings.HasSuffix(m[1], `"`) || strings.HasSuffix(m[1], "'") {
+		quote = m[1][len(m[1])-1:]
+	}
+	if m[3] == "=" {
+		value = strings.TrimSuffix(value, quote)
+	} else {
+		value = strings.Trim(value, `"'`)
+	}
+
+	if !isSecret(name) || value == "" || value == "redacted" || strings.Contains(value, "$") ||
+		strings.ContainsAny(value[:1], "|>[{&*#") {
+		return "", "", false
+	}
+
+	return value, quote, true
+}
+
+// redactYAML replaces the values of secret looking keys in the yaml b, the
+// values of secrets replaced by r elsewhere and the userinfo of URLs with
+// their HMAC, and returns the keys, or name for lines without one.
+func redactYAML(b []byte, name string, key []byte, r *strings.Replacer) ([]byte, []string) {
+
+	var redacted []string
+
+	lines := strings.Split(string(b), "\n")
+	for i, line := range lines {
+		m := yamlSecretRe.FindStringSubmatch(line)
+		if m != nil {
+			if value, quote, ok := yamlSecret(m); ok {
+				if m[3] == "=" {
+					lines[i] = m[1] + m[2] + "=" + redact(key, value) + quote
+				} else {
+					lines[i] = m[1] + m[2] + m[3] + redact(key, value)
+				}
+				redacted = append(redacted, m[2])
+				continue
+			}
+		}
+
+		if lines[i] = redactEmbedded(line, key, r); lines[i] != line {
+			if m != nil {
+				redacted = append(redacted, m[2])
+			} else {
+				redacted = append(redacted, name)
+			}
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n")), redacted
+}
+
+// redact returns a placeholder for value that is equal for equal values.
+func redact(key []byte, value string) string {
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+
+	return "redacted-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// bundleName returns the name fname is stored as in a bundle.
+func bundleName(fname string) string {
+
+	if filepath.IsAbs(fname) || strings.HasPrefix(filepath.Clean(fname), "..") {
+		return filepath.Base(fname)
+	}
+
+	return filepath.ToSlash(filepath.Clean(fname))
+}
+
+// commandVersion returns the trimmed output of a version command, or
+// "unknown" when it fails.
+func commandVersion(name string, args ...string) string {
+
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return "unknown"
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// replay resolves the environment recorded in bundle.
+func replay(bundle string) error {
+
+	dir, err := os.MkdirTemp("", "loadenv-replay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := extractBundle(bundle, dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+
+	// resolve with the recorded settings and files only, instead of this
+	// machine's config and environment, whose values would be expanded
+	flags.dotenvFiles = []string{m.Dotenv}
+	if len(m.DotenvFiles) > 0 {
+		flags.dotenvFiles = m.DotenvFiles
+	}
+	flags.envName = m.Env
+	flags.dialectName = m.Dialect
+	// the bundle is not a checkout of the project
+	flags.forceProject = true
+	if m.Config != "" {
+		viper.SetConfigFile(m.Config)
+		if err := viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("can not read recorded config: %v", err)
+		}
+	} else {
+		viper.SetConfigType("yaml")
+		if err := viper.ReadConfig(strings.NewReader("")); err != nil {
+			return err
+		}
+	}
+	os.Clearenv()
+
+	fmt.Printf("# recorded %s by loadenv %s on %s\n", m.RecordedAt.Format(time.RFC3339), m.Version, m.Platform)
+	fmt.Printf("# docker %s, docker-compose %s, dialect %q\n", m.Docker, m.Compose, m.Dialect)
+
+	vars, err := parseEnvFile(m.Dotenv)
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if _, ok := m.HostEnv[v.Key]; ok {
+			fmt.Printf("# %s was also set in the host environment\n", v.Key)
+		}
+	}
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := applySchema(); err != nil {
+		return err
+	}
+
+	// the layers, generated values, aliases, env_file imports and schema
+	// defaults
+	for _, key := range loadedKeys {
+		fmt.Printf("%s=%s\n", key, os.Getenv(key))
+	}
+
+	return nil
+}
+
+// extractBundle extracts the files of bundle into dir and returns its manifest.
+func extractBundle(bundle, dir string) (*recordManifest, error) {
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("can not read %s: %v", bundle, err)
+	}
+
+	var m *recordManifest
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("can not read %s: %v", bundle, err)
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Name == "manifest.json" {
+			m = &recordManifest{}
+			if err := json.Unmarshal(b, m); err != nil {
+				return nil, fmt.Errorf("can not parse manifest of %s: %v", bundle, err)
+			}
+			continue
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "files/")
+		if name == hdr.Name || strings.HasPrefix(filepath.Clean(name), "..") || filepath.IsAbs(name) {
+			return nil, fmt.Errorf("unexpected file %s in %s", hdr.Name, bundle)
+		}
+
+		fname := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(fname, b, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	if m == nil {
+		return nil, fmt.Errorf("%s is not a loadenv record bundle", bundle)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,138 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// remoteEnvFile is where the resolved environment is written on the
+// remote machine, relative to the synced project.
+const remoteEnvFile = ".loadenv/remote.env"
+
+// remoteDir returns the directory the project is synced to on the remote.
+func remoteDir() string {
+
+	if dir := viper.GetString("remote.dir"); dir != "" {
+		return dir
+	}
+
+	return "loadenv/" + projectName()
+}
+
+// startRemote syncs the project to host and runs the compose commands
+// there over ssh. The environment is resolved locally, so secrets
+// providers use local credentials, and is handed to the remote shell via
+// a private file rather than the command line.
+func startRemote(host string) error {
+
+	build, err := buildImages()
+	if err != nil {
+		return err
+	}
+
+	dir := remoteDir()
+
+	if err := ssh(host, false, nil, "mkdir -p "+shellQuote(dir+"/.loadenv")); err != nil {
+		return err
+	}
+
+	args := []string{"-az", "--delete",
+		// generated overrides are needed remotely even when ignored
+		"--include=/.loadenv/", "--include=/.loadenv/tmp/***", "--exclude=/.loadenv/*",
+	}
+	for _, ignore := range []string{".dockerignore", ".gitignore"} {
+		if _, err := os.Stat(ignore); err == nil {
+			args = append(args, "--filter=:- "+ignore)
+		}
+	}
+	args = append(args, "./", host+":"+dir+"/")
+
+	rsyncCmd := exec.Command("rsync", args...)
+	rsyncCmd.Stdout = os.Stdout
+	rsyncCmd.Stderr = os.Stderr
+	if err := timePhase("sync", rsyncCmd.Run); err != nil {
+		return fmt.Errorf("can not sync project to %s: %v", host, err)
+	}
+
+	// everything loadenv resolved, generated values and aliases included
+	var env bytes.Buffer
+	exported := make(map[string]bool)
+	for _, key := range append(append([]string(nil), loadedKeys...), viteKeys()...) {
+		if exported[key] {
+			continue
+		}
+		line, err := exportVar(exportFormats["sh"], key, os.Getenv(key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(&env, line)
+		exported[key] = true
+	}
+
+	if err := ssh(host, false, &env, "umask 077 && cat > "+shellQuote(dir+"/"+remoteEnvFile)); err != nil {
+		return err
+	}
+
+	steps := [][]string{upArgs()}
+	if build {
+		steps = append([][]string{{"build"}}, steps...)
+	}
+
+	var script []string
+	for _, args := range steps {
+		c := composeCommand(args...)
+		var quoted []string
+		for _, arg := range c.Args {
+			quoted = append(quoted, shellQuote(arg))
+		}
+		script = append(script, strings.Join(quoted, " "))
+	}
+
+	return timePhase("up", func() error {
+		return ssh(host, true, nil, "cd "+shellQuote(dir)+" && . "+remoteEnvFile+" && "+strings.Join(script, " && "))
+	})
+}
+
+// ssh runs the shell command on host, streaming its output. When stdin is
+// nil the local stdin is forwarded.
+func ssh(host string, tty bool, stdin io.Reader, command string) error {
+
+	args := []string{host, command}
+	if tty {
+		args = append([]string{"-t"}, args...)
+	}
+
+	sshCmd := exec.Command("ssh", args...)
+	sshCmd.Stdout = os.Stdout
+	sshCmd.Stderr = os.Stderr
+	sshCmd.Stdin = os.Stdin
+	if stdin != nil {
+		sshCmd.Stdin = stdin
+	}
+
+	return sshCmd.Run()
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,138 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewRestartCmd returns the restart command.
+func NewRestartCmd() *cobra.Command {
+
+	restartCmd := &cobra.Command{
+		Use:   "restart [service...]",
+		Short: "Restart the stack's containers",
+		Long: `Restart restarts the stack's containers, or those of the given services.
+Restarted containers keep the environment they were created with, so an
+edited dotenv file does not take effect.
+
+With --recreate-on-change the dotenv file is read again, and when the
+resolved variables differ from the ones the stack was started with the
+containers are recreated with the new values instead.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := restart(args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	restartCmd.Flags().BoolVar(&flags.restartRecreate, "recreate-on-change", false, "recreate the containers when the variables changed")
+
+	return restartCmd
+}
+
+// restart restarts services of the recorded stack, recreating them when
+// asked to and the environment changed.
+func restart(services []string) error {
+
+	st, err := attachState()
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return fmt.Errorf("no stack started by loadenv in the local directory")
+	}
+
+	for _, name := range services {
+		if len(st.Services) > 0 && !hasString(st.Services, name) {
+			return fmt.Errorf("the stack has no service %s", name)
+		}
+	}
+
+	if flags.restartRecreate {
+		changed, err := envChanged(st)
+		if err != nil {
+			return err
+		}
+		if changed {
+			return recreate(st, services)
+		}
+		info("The variables did not change, restarting\n")
+	}
+
+	c := composeCommand(append([]string{"restart"}, services...)...)
+
+	return timePhase("restart", func() error { return runDocker(c) })
+}
+
+// envChanged loads the dotenv file and reports whether the resolved
+// variables differ from the ones st was started with.
+func envChanged(st *state) (bool, error) {
+
+	h, err := currentEnvHash()
+	if err != nil {
+		return false, err
+	}
+
+	// stacks started before the hash was recorded are always recreated
+	return h != st.EnvHash, nil
+}
+
+// currentEnvHash loads the dotenv file and returns the envHash of the
+// resolved variables.
+func currentEnvHash() (string, error) {
+
+	if err := loadEnvironment(); err != nil {
+		return "", err
+	}
+
+	if err := setupSchema(); err != nil {
+		return "", err
+	}
+
+	return loadedEnvHash()
+}
+
+// recreate recreates services of the stack with the loaded variables.
+// The hash is only recorded when the whole stack was recreated, as other
+// services still run with the old values.
+func recreate(st *state, services []string) error {
+
+	info("The variables changed, recreating the containers\n")
+
+	if !inheritsEnv() {
+		if err := writeComposeEnvFile(); err != nil {
+			return err
+		}
+	}
+
+	args := append([]string{"up", "-d", "--no-build", "--force-recreate"}, services...)
+	if err := timePhase("up", func() error { return runDocker(composeCommand(args...)) }); err != nil {
+		return err
+	}
+	if len(services) > 0 {
+		return nil
+	}
+
+	var err error
+	if st.EnvHash, err = loadedEnvHash(); err != nil {
+		return err
+	}
+
+	return writeState(st)
+}
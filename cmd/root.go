@@ -14,84 +14,277 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/shaybix/loadenv/pkg/loadenv"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile    string
-	dotenvFile string
-	envConfig  map[string]string
+	envConfig map[string]string
 )
 
-// RootCmd represents the base command when called without any subcommands
-var RootCmd = &cobra.Command{
-	Use:   "loadenv",
-	Short: "Loadenv loads environment for a laravel project using Docker",
-	Long:  ``,
-	// Uncomment the following line if your bare application
-	// has an action associated with it:
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := load(); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
-	},
+// Options customises the command tree built by NewRootCmd, so other
+// CLIs can mount loadenv's commands under their own binary.
+type Options struct {
+	// Use is the name of the root command, "loadenv" by default.
+	Use string
+	// DotenvFile is the dotenv file used when --dotenv is not given,
+	// ".env" by default.
+	DotenvFile string
+	// ConfigName is the name of the config file searched for in the
+	// home directory, without extension. ".loadenv" by default.
+	ConfigName string
+	// Events are called back on the lifecycle events of the commands.
+	Events *loadenv.Events
 }
 
-// Execute adds all child commands to the root command and sets flags appropriately.
-// This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() {
-	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// Version is the loadenv version, set at build time with
+// -ldflags "-X github.com/shaybix/loadenv/cmd.Version=...".
+var Version = "dev"
+
+// NewRootCmd returns the base command, which starts the stack like up when
+// called without any subcommands, with all subcommands added. The tree
+// owns its flag values, and reads the config only once one of its
+// commands runs, so it can be mounted under another CLI.
+func NewRootCmd(opts Options) *cobra.Command {
+
+	if opts.Use == "" {
+		opts.Use = "loadenv"
 	}
-}
 
-func init() {
-	cobra.OnInitialize(initConfig)
+	f := newFlagValues()
+	if opts.DotenvFile != "" {
+		f.defaultDotenv = opts.DotenvFile
+	}
+	if opts.ConfigName != "" {
+		f.configName = opts.ConfigName
+	}
+	f.events = opts.Events
+	// the subcommands bind their flags to the values in use
+	flags = f
+
+	rootCmd := &cobra.Command{
+		Use:   opts.Use,
+		Short: "Loadenv loads environment for a laravel project using Docker",
+		Long:  ``,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+
+			flags = f
+			if err := initConfig(); err != nil {
+				// Execute, or the CLI mounting the tree, reports it
+				cmd.SilenceErrors, cmd.SilenceUsage = true, true
+				return err
+			}
+			stopExpired(cmd)
+
+			// every subcommand is timed and recorded in the history,
+			// see timing.go and history.go
+			startCommand(cmd, args)
+			return nil
+		},
+		PersistentPostRun: finishCommand,
+		Args:              passthroughArgs(&upPassthrough),
+		// Uncomment the following line if your bare application
+		// has an action associated with it:
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := load(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
-	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.loadenv.yaml)")
+	rootCmd.PersistentFlags().StringVar(&flags.cfgFile, "config", "", "config file (default is $HOME/"+flags.configName+".yaml, with ./"+flags.configName+".yaml merged over it)")
+	rootCmd.PersistentFlags().StringArrayVar(&flags.dotenvFiles, "dotenv", nil, "dotenv file with environment variables, repeat to override it with more files (default is "+flags.defaultDotenv+")")
+	rootCmd.PersistentFlags().BoolVar(&flags.noExpand, "no-expand", false, "do not expand ${VAR} references in dotenv values")
+	rootCmd.PersistentFlags().BoolVarP(&flags.quiet, "quiet", "q", false, "do not print informational messages")
+	rootCmd.PersistentFlags().BoolVar(&flags.verbose, "verbose", false, "print which file each variable was loaded from")
+	rootCmd.PersistentFlags().StringVar(&flags.langFlag, "lang", "", "language of messages (default is from LANG)")
+	rootCmd.PersistentFlags().BoolVar(&flags.showTimings, "timings", false, "print how long each phase of the command took")
+	rootCmd.PersistentFlags().StringVar(&flags.composeChoice, "compose", "", "compose implementation to use (auto|plugin|standalone)")
+	rootCmd.PersistentFlags().StringArrayVar(&flags.composeArgs, "compose-arg", nil, "pass a global option to compose, e.g. --compose-arg=--profile=debug (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&flags.forceProject, "force", false, "skip checking that the working directory and dotenv files belong to the project")
+	rootCmd.PersistentFlags().BoolVar(&flags.inheritEnv, "inherit-env", false, "pass variables to compose through its environment instead of --env-file")
+	rootCmd.PersistentFlags().StringVarP(&flags.envName, "env", "e", "", "environment whose "+flags.defaultDotenv+".<env> files are layered over "+flags.defaultDotenv)
+	rootCmd.PersistentFlags().StringVar(&flags.dialectName, "dialect", "", "dotenv syntax to parse files with (posix|docker|ruby|node)")
+	rootCmd.PersistentFlags().DurationVar(&flags.dockerTimeout, "timeout", 0, "stop docker and compose commands that run longer than this, e.g. 10m; attached stacks, exec and logs -f are not limited")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
-	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	RootCmd.Flags().StringVar(&dotenvFile, "dotenv", "", "dotenv file with environment variables")
+	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	// the bare command is an alias of up, so it takes the same flags
+	addUpFlags(rootCmd.Flags())
+
+	rootCmd.AddCommand(
+		NewAllowCmd(),
+		NewChaosCmd(),
+		NewCheckCmd(),
+		NewConvertCmd(),
+		NewCopyCmd(),
+		NewDebugCmd(),
+		NewDenyCmd(),
+		NewDevcontainerCmd(),
+		NewDiffCmd(),
+		NewDoctorCmd(),
+		NewDownCmd(),
+		NewDuCmd(),
+		NewEnvlogCmd(),
+		NewExampleCmd(),
+		NewExecCmd(),
+		NewExportCmd(),
+		NewFaketimeCmd(),
+		NewGcCmd(),
+		NewGetCmd(),
+		NewGraphCmd(),
+		NewHashCmd(),
+		NewHistoryCmd(),
+		NewHookCmd(),
+		NewImportCmd(),
+		NewInstrumentCmd(),
+		NewLintCmd(),
+		NewLoadtestCmd(),
+		NewLogsCmd(),
+		NewMatrixCmd(),
+		NewMockCmd(),
+		NewPauseCmd(),
+		NewProbeCmd(),
+		NewProfileCmd(),
+		NewRecordCmd(),
+		NewReplayCmd(),
+		NewRerunCmd(),
+		NewRestartCmd(),
+		NewResumeCmd(),
+		NewRunCmd(),
+		NewSbomCmd(),
+		NewScanCmd(),
+		NewScheduleWorkCmd(),
+		NewSearchCmd(),
+		NewSetCmd(),
+		NewShellCmd(),
+		NewSignCmd(),
+		NewStatusCmd(),
+		NewUnsetCmd(),
+		NewUnusedCmd(),
+		NewUpCmd(),
+		NewValidateCmd(),
+	)
+
+	return rootCmd
+}
+
+// Execute builds the root command and executes it.
+// This is called by main.main(). It only needs to happen once.
+func Execute() {
+	if err := NewRootCmd(Options{}).Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+}
+
+// userOnlyKeys are the settings a project config can not change, as the
+// project is not trusted with them. They come from the user's config, the
+// environment or --config.
+var userOnlyKeys = []string{
+	"readonly",
+	"hash.hmac_key",
+	"signatures.verify",
+	"signatures.key",
+	"signatures.allowed_signers",
 }
 
 // initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
+func initConfig() error {
+
+	viper.AutomaticEnv() // read in environment variables that match
+
+	if flags.cfgFile != "" {
 		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
+		viper.SetConfigFile(flags.cfgFile)
+		if err := viper.ReadInConfig(); err == nil {
+			info("Using config file: %s\n", viper.ConfigFileUsed())
+		}
+		return checkComposeChoice()
+	}
+
+	// Find home directory.
+	home, err := homedir.Dir()
+	if err != nil {
+		return err
+	}
+
+	// Read the config in the home directory with name ".loadenv" (without
+	// extension), then merge the one of the project directory over it.
+	viper.AddConfigPath(home)
+	viper.SetConfigName(flags.configName)
+	if err := viper.ReadInConfig(); err == nil {
+		info("Using config file: %s\n", viper.ConfigFileUsed())
+	}
+
+	project := viper.New()
+	project.AddConfigPath(".")
+	project.SetConfigName(flags.configName)
+	if err := project.ReadInConfig(); err == nil {
+		fname, err := realPath(project.ConfigFileUsed())
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
+		used, err := realPath(viper.ConfigFileUsed())
+		if err != nil {
+			return err
+		}
+		if fname != used {
+			if err := mergeProjectConfig(project.ConfigFileUsed()); err != nil {
+				return err
+			}
+			info("Using config file: %s\n", viper.ConfigFileUsed())
+		}
+	}
+
+	return checkComposeChoice()
+}
+
+// mergeProjectConfig merges the project config fname over the config read
+// so far, keeping the userOnlyKeys as they were.
+func mergeProjectConfig(fname string) error {
 
-		// Search config in home directory with name ".loadenv" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".loadenv")
+	user := make(map[string]interface{})
+	for _, key := range userOnlyKeys {
+		user[key] = viper.Get(key)
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	viper.SetConfigFile(fname)
+	if err := viper.MergeInConfig(); err != nil {
+		return fmt.Errorf("can not read %s: %v", fname, err)
+	}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	for key, value := range user {
+		if value == nil {
+			value = ""
+		}
+		viper.Set(key, value)
+	}
+
+	return nil
+}
+
+// info prints an informational message to stderr unless --quiet is set,
+// keeping stdout for command output.
+func info(format string, args ...interface{}) {
+
+	if !flags.quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
 	}
 }
 
@@ -99,93 +292,357 @@ func initConfig() {
 // flag has been set.
 func load() error {
 
-	var fname string
-
-	if dotenvFile != "" {
-		// load file
-		fname = dotenvFile
-	} else {
-		fname = ".env"
+	if err := maybeRunWizard(); err != nil {
+		return err
 	}
 
+	fname := dotenvFileName()
+
 	if _, err := os.Stat(fname); os.IsNotExist(err) {
 		return fmt.Errorf("can not find %s file in the local directory", fname)
 	}
 
-	if _, err := os.Stat("Dockerfile"); os.IsNotExist(err) {
-		return fmt.Errorf("can not find Dockerfile file in the local directory")
+	// stacks of prebuilt images have nothing to build
+	if !flags.noDockerCheck {
+		if missing := missingDockerfiles(); len(missing) > 0 {
+			return fmt.Errorf("can not find %s, use --no-docker-check if the compose file does not need it", strings.Join(missing, ", "))
+		}
+	}
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := setupSchema(); err != nil {
+		return err
+	}
+
+	checkLimits()
+
+	if err := setupPlatform(); err != nil {
+		return err
+	}
+
+	if flags.userSuffix {
+		if err := applyUserSuffix(); err != nil {
+			return err
+		}
+	}
+
+	if flags.withNode {
+		if err := setupNode(); err != nil {
+			return err
+		}
+	}
+
+	if flags.withSync {
+		if err := setupSync(); err != nil {
+			return err
+		}
+	}
+
+	if flags.perfMode != "" {
+		if err := setupPerf(flags.perfMode); err != nil {
+			return err
+		}
+	}
+
+	if err := setupFaketime(); err != nil {
+		return err
+	}
+
+	if err := setupDebug(); err != nil {
+		return err
+	}
+
+	if err := setupMocks(); err != nil {
+		return err
+	}
+
+	if err := timePhase("policies", func() error { return checkPolicies(fname) }); err != nil {
+		return err
+	}
+
+	if err := enforceBaseImages(); err != nil {
+		return err
 	}
 
-	if err := loadEnvVars(fname); err != nil {
+	if err := setupLabels(); err != nil {
 		return err
 	}
 
+	if flags.remoteHost != "" {
+		if flags.upWait {
+			return fmt.Errorf("can not use --wait with --remote")
+		}
+		return startRemote(flags.remoteHost)
+	}
+
+	// waiting needs the stack in the background
+	if flags.upWait {
+		flags.upDetach = true
+	}
+
 	if err := startDocker(); err != nil {
 		return err
 	}
 
+	if flags.upWait {
+		if err := waitStack(); err != nil {
+			return err
+		}
+	}
+
+	// an attached stack has already stopped, so only a detached one is
+	// summarised
+	if flags.upDetach {
+		printSummary(runningStack())
+	}
+
 	return nil
 }
 
-//loadEnvVars will load environment variables from file
+// checkReadOnly returns an error when the readonly config is set, for
+// commands that would mutate files or remote stores.
+func checkReadOnly(action string) error {
+
+	if viper.GetBool("readonly") {
+		return fmt.Errorf("%s is disabled in read-only mode", action)
+	}
+
+	return nil
+}
+
+// dotenvFileName returns the first dotenv file given with --dotenv, or the
+// default.
+func dotenvFileName() string {
+
+	if len(flags.dotenvFiles) > 0 {
+		return flags.dotenvFiles[0]
+	}
+
+	return flags.defaultDotenv
+}
+
+// loadEnvironment loads the dotenv file and its layers, along with the
+// generated values and aliases. Every command that resolves the
+// environment loads it this way, so they all see the same one. Applying
+// or validating the schema is left to the caller. The Resolved event is
+// emitted once it is loaded.
+func loadEnvironment() error {
+
+	if err := loadEnvVars(dotenvFileName()); err != nil {
+		return err
+	}
+
+	if err := setupGenerated(); err != nil {
+		return err
+	}
+
+	if err := setupAliases(); err != nil {
+		return err
+	}
+
+	vars := make(map[string]string)
+	for _, key := range loadedKeys {
+		vars[key] = os.Getenv(key)
+	}
+
+	return flags.events.Emit(loadenv.Resolved{Files: dotenvLayers(dotenvFileName()), Vars: vars})
+}
+
+// loadEnvVars will load environment variables from file and its layers,
+// in order, so later layers override, or merge with, and can refer to
+// earlier ones. When signatures are required every file is verified
+// before the first is loaded.
 func loadEnvVars(fname string) error {
 
-	f, err := os.Open(fname)
-	if err != nil {
+	layers := dotenvLayers(fname)
+	if err := guardProject(layers...); err != nil {
 		return err
 	}
 
-	defer f.Close()
+	if signaturesRequired() {
+		if err := verifyLayers(layers); err != nil {
+			return err
+		}
+	}
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
+	origin := make(map[string]string)
+	earlier := make(map[string]string)
+	for _, layer := range layers {
+		vars, err := parseEnvFile(layer)
+		if err != nil {
+			return err
+		}
+		if vars, err = mergeLayer(earlier, vars); err != nil {
+			return err
+		}
 
-		if strings.HasPrefix(line, "#") {
-			continue
-		} else {
-			envVar := strings.Split(line, "=")
+		if err := warnDeprecated(layer, vars); err != nil {
+			return err
+		}
 
-			if err := os.Setenv(envVar[0], envVar[1]); err != nil {
+		for _, v := range vars {
+			if err := setEnv(v.Key, v.Value); err != nil {
 				return err
 			}
+			origin[v.Key] = layer
+		}
+	}
+
+	if err := loadServiceEnvFiles(layers, origin); err != nil {
+		return err
+	}
+
+	if flags.verbose {
+		for _, key := range loadedKeys {
+			if layer, ok := origin[key]; ok {
+				info("%s from %s\n", key, layer)
+			}
 		}
 	}
 
 	return nil
 }
 
+// envVar is a single variable read from a dotenv file.
+type envVar = dotenv.Var
+
+// parseEnvFile reads the variables from a dotenv file in file order.
+func parseEnvFile(fname string) ([]envVar, error) {
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return parseEnv(f, fname)
+}
+
+// parseEnv reads dotenv formatted variables from r in order. name is used
+// in error messages.
+func parseEnv(r io.Reader, name string) ([]envVar, error) {
+
+	d, err := selectedDialect()
+	if err != nil {
+		return nil, err
+	}
+
+	return dotenv.Read(r, name, d, !flags.noExpand)
+}
+
+// parseEnvDialect is parseEnv using the syntax of dialect d, or loadenv's
+// own syntax if d is nil, without expanding variable references.
+func parseEnvDialect(r io.Reader, name string, d *dotenv.Dialect) ([]envVar, error) {
+	return dotenv.Read(r, name, d, false)
+}
+
 // startDocker will orchestrate the docker containers by executing the docker-compose
-// command in the shell.
+// command in the shell. A detached stack past its --ttl is stopped by the
+// next loadenv invocation instead of a timer.
 func startDocker() error {
 
-	dockerComposeBuildCmd := exec.Command("docker-compose", "build", ".")
-	dockerComposeBuildCmd.Stdout = os.Stdout
-	dockerComposeBuildCmd.Stderr = os.Stderr
-	if err := dockerComposeBuildCmd.Run(); err != nil {
+	build, err := buildImages()
+	if err != nil {
+		return err
+	}
+
+	if !inheritsEnv() {
+		if err := writeComposeEnvFile(); err != nil {
+			return err
+		}
+	}
+
+	if err := recordState(); err != nil {
+		return err
+	}
+
+	s := newStopper()
+	err = startStack(s, build)
+	s.release()
+	if errors.Is(err, errStopped) {
+		return stoppedStack()
+	}
+
+	return err
+}
+
+// startStack builds, scans and starts the stack, running the steps with s
+// so they can be stopped by a signal.
+func startStack(s *stopper, build bool) error {
+
+	if build {
+		dockerComposeBuildCmd := composeCommand("build")
+		if err := timePhase("build", func() error { return s.run(dockerComposeBuildCmd, true) }); err != nil {
+			return err
+		}
+	}
+
+	// scan after building so the built images are scanned too
+	if flags.scanBeforeUp || viper.GetBool("scan.before_up") {
+		if err := timePhase("scan", scan); err != nil {
+			return err
+		}
+		if s.stopped() {
+			return errStopped
+		}
+	}
+
+	if err := flags.events.Emit(loadenv.BeforeUp{Project: stackProject(), Args: upArgs()}); err != nil {
 		return err
 	}
 
-	dockerComposeUpCmd := exec.Command("docker-compose", "up")
-	dockerComposeUpCmd.Stdout = os.Stdout
-	dockerComposeUpCmd.Stderr = os.Stderr
+	dockerComposeUpCmd := composeCommand(upArgs()...)
 
-	if err := dockerComposeUpCmd.Run(); err != nil {
+	if flags.stackTTL > 0 && !flags.upDetach {
+		// stopping the containers makes the attached up return
+		timer := time.AfterFunc(flags.stackTTL, func() {
+			info("The stack reached its --ttl of %s, stopping it\n", flags.stackTTL)
+			runDocker(composeCommand("stop"))
+		})
+		defer timer.Stop()
+	}
+
+	// an attached stack runs until it is stopped
+	if err := timePhase("up", func() error { return s.run(dockerComposeUpCmd, flags.upDetach) }); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// stoppedStack cleans up after the stack was stopped by a signal. The
+// stopped containers are removed with --down-on-stop, and kept for
+// loadenv down otherwise.
+func stoppedStack() error {
+
+	if flags.upDownOnStop || viper.GetBool("up.down_on_stop") {
+		info("Stopped, removing the stack\n")
+		if err := stopDocker(); err != nil {
+			return err
+		}
+	} else {
+		info("Stopped, remove the containers with loadenv down\n")
+	}
+
+	return errStopped
+}
+
 // stopDocker stops docker environment for the project in the
-// current working directory
-func stopDocker() error {
+// current working directory, passing args to docker-compose down
+func stopDocker(args ...string) error {
+
+	// Tear down exactly what was recorded when the stack was started.
+	if _, err := attachState(); err != nil {
+		return err
+	}
 
-	dockerComposeDownCmd := exec.Command("docker-compose", "down")
-	dockerComposeDownCmd.Stdout = os.Stdout
-	dockerComposeDownCmd.Stderr = os.Stderr
+	dockerComposeDownCmd := composeCommand(append([]string{"down"}, args...)...)
 
-	if err := dockerComposeDownCmd.Run(); err != nil {
+	if err := runDocker(dockerComposeDownCmd); err != nil {
 		return err
 	}
 
@@ -199,7 +656,28 @@ func stopDocker() error {
 // cleanup will clean up files/directory created
 func cleanup() error {
 
+	st, err := readState()
+	if err != nil {
+		return err
+	}
+	if st != nil {
+		for _, fname := range st.TempFiles {
+			if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
 	// remove tmp dir in the project folder
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	overrideFiles = nil
 
 	return nil
 }
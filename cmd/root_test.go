@@ -0,0 +1,56 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestInitConfigMergesProjectConfig(t *testing.T) {
+
+	dir := testProject(t)
+
+	user := "readonly: true\nhash:\n  hmac_key: user-key\ndocker:\n  retries: 2\n"
+	if err := os.WriteFile(filepath.Join(os.Getenv("HOME"), ".loadenv.yaml"), []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project := "app_service: app\nreadonly: false\nsignatures:\n  allowed_signers: /tmp/signers\n"
+	if err := os.WriteFile(filepath.Join(dir, ".loadenv.yaml"), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := initConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := viper.GetString("app_service"); got != "app" {
+		t.Errorf("app_service = %q, want app from the project config", got)
+	}
+	if got := viper.GetInt("docker.retries"); got != 2 {
+		t.Errorf("docker.retries = %d, want 2 from the user config", got)
+	}
+	if err := checkReadOnly("set"); err == nil {
+		t.Error("checkReadOnly succeeded, want the user config's readonly to win")
+	}
+	if got := viper.GetString("hash.hmac_key"); got != "user-key" {
+		t.Errorf("hash.hmac_key = %q, want user-key from the user config", got)
+	}
+	if got := viper.GetString("signatures.allowed_signers"); got != "" {
+		t.Errorf("signatures.allowed_signers = %q, want it unset as only the project config sets it", got)
+	}
+}
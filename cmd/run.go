@@ -0,0 +1,101 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("run.keep_env", []string{"PATH", "HOME", "TERM"})
+}
+
+// NewRunCmd returns the run command.
+func NewRunCmd() *cobra.Command {
+
+	runCmd := &cobra.Command{
+		Use:   "run -- <command> [args...]",
+		Short: "Run a command with the dotenv variables in its environment",
+		Long: `Run loads the dotenv file and runs the given command with its variables
+added to the environment, e.g. loadenv run -- php artisan migrate. Only the
+command sees the variables, and loadenv exits with its exit code.
+
+With --clean-env the command gets only the loaded variables and those
+listed under run.keep_env in the config, by default PATH, HOME and TERM,
+plus any given with --keep-env. A command that depends on a variable of
+the developer's machine then fails the same way it would in CI.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(args); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exit(exitErr.ExitCode())
+				}
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	runCmd.Flags().BoolVar(&flags.runCleanEnv, "clean-env", false, "run the command with only the loaded variables and run.keep_env")
+	runCmd.Flags().StringSliceVar(&flags.runKeepEnv, "keep-env", nil, "also keep this variable of the environment with --clean-env")
+
+	// everything after the command belongs to it
+	runCmd.Flags().SetInterspersed(false)
+
+	return runCmd
+}
+
+// run runs args with the loaded environment.
+func run(args []string) error {
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	if err := applySchema(); err != nil {
+		return err
+	}
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = os.Environ()
+	if flags.runCleanEnv {
+		c.Env = cleanEnv()
+	}
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return timePhase("run", c.Run)
+}
+
+// cleanEnv returns the loaded variables and the variables to keep, in
+// the form of os.Environ.
+func cleanEnv() []string {
+
+	var env []string
+	for _, key := range append(append(viper.GetStringSlice("run.keep_env"), flags.runKeepEnv...), loadedKeys...) {
+		if value, ok := os.LookupEnv(key); ok && !hasString(env, key+"="+value) {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	return env
+}
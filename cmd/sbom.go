@@ -0,0 +1,232 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewSbomCmd returns the sbom command.
+func NewSbomCmd() *cobra.Command {
+
+	sbomCmd := &cobra.Command{
+		Use:   "sbom",
+		Short: "Write a manifest of the development environment",
+		Long: `Sbom lists the images of the stack with their digests, the hashes of the
+compose files, the names of the non-secret variables and the versions of
+the host tools, as CycloneDX or loadenv's own JSON.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := sbom(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	sbomCmd.Flags().StringVar(&flags.sbomFormat, "format", "cyclonedx", "output format (cyclonedx|json)")
+	sbomCmd.Flags().StringVarP(&flags.sbomOutput, "output", "o", "", "file to write to (default is stdout)")
+
+	return sbomCmd
+}
+
+// envManifest describes the development environment.
+type envManifest struct {
+	Project      string            `json:"project"`
+	GeneratedAt  time.Time         `json:"generated_at"`
+	Images       map[string]string `json:"images"`
+	ImageDigests map[string]string `json:"image_digests"`
+	ComposeFiles map[string]string `json:"compose_files"`
+	EnvKeys      []string          `json:"env_keys"`
+	Tools        map[string]string `json:"tools"`
+}
+
+// sbom writes the manifest in the selected format.
+func sbom() error {
+
+	m, err := buildEnvManifest()
+	if err != nil {
+		return err
+	}
+
+	var v interface{}
+	switch flags.sbomFormat {
+	case "json":
+		v = m
+	case "cyclonedx":
+		v = cycloneDX(m)
+	default:
+		return fmt.Errorf("unknown --format %q, use cyclonedx or json", flags.sbomFormat)
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if flags.sbomOutput == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	if err := checkReadOnly("sbom -o"); err != nil {
+		return err
+	}
+
+	return os.WriteFile(flags.sbomOutput, b, 0644)
+}
+
+// buildEnvManifest collects the manifest of the project's environment.
+func buildEnvManifest() (*envManifest, error) {
+
+	m := &envManifest{
+		Project:      stackProject(),
+		GeneratedAt:  time.Now().UTC(),
+		Images:       stackImages(),
+		ImageDigests: make(map[string]string),
+		ComposeFiles: make(map[string]string),
+		Tools: map[string]string{
+			"loadenv":        Version,
+			"platform":       runtime.GOOS + "/" + runtime.GOARCH,
+			"docker":         commandVersion("docker", "version", "--format", "{{.Server.Version}}"),
+			"docker-compose": composeVersion(),
+		},
+	}
+
+	for _, image := range m.Images {
+		if _, ok := m.ImageDigests[image]; ok {
+			continue
+		}
+		// images that were never pulled or built have no digest
+		m.ImageDigests[image] = ""
+		if out, err := dockerLines("image", "inspect", "-f", "{{if .RepoDigests}}{{index .RepoDigests 0}}{{else}}{{.ID}}{{end}}", image); err == nil && len(out) > 0 {
+			digest := out[0]
+			if i := strings.Index(digest, "@"); i >= 0 {
+				digest = digest[i+1:]
+			}
+			m.ImageDigests[image] = digest
+		}
+	}
+
+	for _, fname := range composeFiles() {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		m.ComposeFiles[fname] = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	if vars, err := parseEnvLayers(dotenvFileName()); err == nil {
+		seen := make(map[string]bool)
+		for _, v := range vars {
+			if !isSecret(v.Key) && !seen[v.Key] {
+				seen[v.Key] = true
+				m.EnvKeys = append(m.EnvKeys, v.Key)
+			}
+		}
+		sort.Strings(m.EnvKeys)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// cycloneDX returns m as a CycloneDX 1.4 bom, with images as container
+// components, compose files as file components and the rest as properties.
+func cycloneDX(m *envManifest) map[string]interface{} {
+
+	type hash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+	type component struct {
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+		Hashes  []hash `json:"hashes,omitempty"`
+	}
+	type property struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	var images []string
+	for image := range m.ImageDigests {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	var components []component
+	for _, image := range images {
+		c := component{Type: "container", Name: image}
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			c.Name, c.Version = image[:i], image[i+1:]
+		}
+		if d := m.ImageDigests[image]; strings.HasPrefix(d, "sha256:") {
+			c.Hashes = []hash{{Alg: "SHA-256", Content: strings.TrimPrefix(d, "sha256:")}}
+		}
+		components = append(components, c)
+	}
+
+	var files []string
+	for fname := range m.ComposeFiles {
+		files = append(files, fname)
+	}
+	sort.Strings(files)
+	for _, fname := range files {
+		components = append(components, component{
+			Type:   "file",
+			Name:   fname,
+			Hashes: []hash{{Alg: "SHA-256", Content: strings.TrimPrefix(m.ComposeFiles[fname], "sha256:")}},
+		})
+	}
+
+	var properties []property
+	for _, key := range m.EnvKeys {
+		properties = append(properties, property{Name: "loadenv:env-key", Value: key})
+	}
+	var tools []string
+	for tool := range m.Tools {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		properties = append(properties, property{Name: "loadenv:tool:" + tool, Value: m.Tools[tool]})
+	}
+
+	return map[string]interface{}{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.4",
+		"version":     1,
+		"metadata": map[string]interface{}{
+			"timestamp": m.GeneratedAt.Format(time.RFC3339),
+			"tools":     []map[string]string{{"name": "loadenv", "version": Version}},
+			"component": map[string]string{"type": "application", "name": m.Project},
+		},
+		"components": components,
+		"properties": properties,
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("scan.severity", "CRITICAL")
+	viper.SetDefault("scan.allowlist", ".loadenv-allowlist")
+}
+
+// severities in increasing order.
+var severities = []string{"LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// NewScanCmd returns the scan command.
+func NewScanCmd() *cobra.Command {
+
+	scanCmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Scan the stack's images for vulnerabilities",
+		Long: `Scan runs trivy, or grype when trivy is not installed, against every image
+the stack uses and fails when a vulnerability of scan.severity or above is
+found that is not listed in the scan.allowlist file, one id per line.
+Set scan.scanner to pick the tool, and scan.before_up or --scan to scan
+before the stack starts.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := scan(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return scanCmd
+}
+
+// finding is a single vulnerability found in an image.
+type finding struct {
+	id       string
+	pkg      string
+	severity string
+}
+
+// scan scans the images of the stack and returns an error on findings.
+func scan() error {
+
+	scanner := viper.GetString("scan.scanner")
+	if scanner == "" {
+		if _, err := exec.LookPath("trivy"); err == nil {
+			scanner = "trivy"
+		} else if _, err := exec.LookPath("grype"); err == nil {
+			scanner = "grype"
+		} else {
+			return fmt.Errorf("can not find trivy or grype in PATH")
+		}
+	}
+
+	minimum := severityRank(viper.GetString("scan.severity"))
+	if minimum < 0 {
+		return fmt.Errorf("unknown scan.severity %q, use %s", viper.GetString("scan.severity"), strings.Join(severities, ", "))
+	}
+
+	allowed, err := readAllowlist(viper.GetString("scan.allowlist"))
+	if err != nil {
+		return err
+	}
+
+	images := stackImages()
+	var names []string
+	seen := make(map[string]bool)
+	for _, image := range images {
+		if !seen[image] {
+			seen[image] = true
+			names = append(names, image)
+		}
+	}
+	sort.Strings(names)
+
+	total := 0
+	for _, image := range names {
+		findings, err := scanImage(scanner, image)
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		skipped := 0
+		for _, f := range findings {
+			if severityRank(f.severity) < minimum {
+				continue
+			}
+			if allowed[f.id] {
+				skipped++
+				continue
+			}
+			ids = append(ids, f.id+" ("+f.pkg+")")
+		}
+		sort.Strings(ids)
+
+		switch {
+		case len(ids) == 0 && skipped == 0:
+			fmt.Printf("ok    %s\n", image)
+		case len(ids) == 0:
+			fmt.Printf("ok    %s, %d allowed\n", image, skipped)
+		default:
+			fmt.Printf("fail  %s: %d %s or above\n", image, len(ids), strings.ToLower(severities[minimum]))
+			for _, id := range ids {
+				fmt.Printf("        %s\n", id)
+			}
+		}
+		total += len(ids)
+	}
+
+	if total > 0 {
+		return fmt.Errorf("scan found %d vulnerabilities, add accepted ones to %s", total, viper.GetString("scan.allowlist"))
+	}
+
+	return nil
+}
+
+// scanImage runs scanner against image and returns what it found.
+func scanImage(scanner, image string) ([]finding, error) {
+
+	var c *exec.Cmd
+	switch scanner {
+	case "trivy":
+		c = exec.Command("trivy", "image", "--quiet", "--format", "json", image)
+	case "grype":
+		c = exec.Command("grype", "--quiet", "-o", "json", image)
+	default:
+		return nil, fmt.Errorf("unknown scan.scanner %q, use trivy or grype", scanner)
+	}
+	c.Stderr = os.Stderr
+
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("can not scan %s with %s: %v", image, scanner, err)
+	}
+
+	var findings []finding
+
+	if scanner == "trivy" {
+		var report struct {
+			Results []struct {
+				Vulnerabilities []struct {
+					VulnerabilityID string
+					PkgName         string
+					Severity        string
+				}
+			}
+		}
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, fmt.Errorf("can not parse trivy output for %s: %v", image, err)
+		}
+		for _, r := range report.Results {
+			for _, v := range r.Vulnerabilities {
+				findings = append(findings, finding{id: v.VulnerabilityID, pkg: v.PkgName, severity: v.Severity})
+			}
+		}
+		return findings, nil
+	}
+
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name string `json:"name"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("can not parse grype output for %s: %v", image, err)
+	}
+	for _, m := range report.Matches {
+		findings = append(findings, finding{id: m.Vulnerability.ID, pkg: m.Artifact.Name, severity: m.Vulnerability.Severity})
+	}
+
+	return findings, nil
+}
+
+// severityRank returns the index of severity in severities, or -1.
+func severityRank(severity string) int {
+
+	for i, s := range severities {
+		if strings.EqualFold(s, severity) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// readAllowlist reads the vulnerability ids in fname, one per line with
+// optional # comments. A missing file allows nothing.
+func readAllowlist(fname string) (map[string]bool, error) {
+
+	allowed := make(map[string]bool)
+
+	f, err := os.Open(fname)
+	if os.IsNotExist(err) {
+		return allowed, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if line != "" {
+			allowed[line] = true
+		}
+	}
+
+	return allowed, scanner.Err()
+}
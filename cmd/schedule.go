@@ -0,0 +1,93 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewScheduleWorkCmd returns the schedule:work command.
+func NewScheduleWorkCmd() *cobra.Command {
+
+	scheduleWorkCmd := &cobra.Command{
+		Use:   "schedule:work",
+		Short: "Run the Laravel scheduler in the app container",
+		Long: `Schedule:work lists the scheduled tasks and when they are next due, then
+runs php artisan schedule:work in the running app service until interrupted,
+so no crontab is needed locally.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := scheduleWork(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	return scheduleWorkCmd
+}
+
+// scheduleWork prints the upcoming tasks and runs the scheduler loop.
+func scheduleWork() error {
+
+	if _, err := attachState(); err != nil {
+		return err
+	}
+
+	// compose interpolates the project files with the loaded environment
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	app := viper.GetString("app_service")
+
+	out, err := dockerOutput(composeCommand("exec", "-T", app, "php", "artisan", "schedule:list"))
+	if err != nil {
+		return fmt.Errorf("can not list scheduled tasks in %s: %v", app, err)
+	}
+	printSchedule(string(out))
+
+	return runAttached(composeCommand("exec", app, "php", "artisan", "schedule:work"))
+}
+
+// printSchedule prints each task of the schedule:list output with when it
+// is next due. Output in a format it does not know is printed as is.
+func printSchedule(out string) {
+
+	var tasks [][2]string
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.Index(line, "Next Due:")
+		if i < 0 {
+			continue
+		}
+
+		task := strings.TrimRight(strings.TrimSpace(line[:i]), ". ")
+		tasks = append(tasks, [2]string{task, strings.TrimSpace(line[i+len("Next Due:"):])})
+	}
+
+	if len(tasks) == 0 {
+		info("%s", out)
+		return
+	}
+
+	info("Scheduled tasks:\n")
+	for _, t := range tasks {
+		info("  %s\n    next due %s\n", t[0], t[1])
+	}
+	info("\n")
+}
@@ -0,0 +1,233 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// schemaFile describes the keys a project's environment must define.
+const schemaFile = "loadenv.schema.yaml"
+
+// schema is the contents of the schema file.
+type schema struct {
+	Keys map[string]schemaKey `yaml:"keys"`
+}
+
+// schemaTypes are the types a schema key can have.
+var schemaTypes = []string{"string", "int", "bool", "url", "port", "enum", "json"}
+
+// schemaKey describes a single key of the environment.
+type schemaKey struct {
+	Type        string   `yaml:"type,omitempty"`
+	Required    bool     `yaml:"required,omitempty"`
+	Default     string   `yaml:"default,omitempty"`
+	Enum        []string `yaml:"enum,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Protected   bool     `yaml:"protected,omitempty"`
+	Computed    string   `yaml:"computed,omitempty"`
+}
+
+// inferType guesses the schema type of key from an example value.
+func inferType(key, value string) string {
+
+	if n, err := strconv.Atoi(value); err == nil {
+		if strings.HasSuffix(key, "_PORT") && n > 0 && n < 65536 {
+			return "port"
+		}
+		return "int"
+	}
+
+	if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return "bool"
+	}
+
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		return "url"
+	}
+
+	return "string"
+}
+
+// readSchema reads the schema file. It returns nil and no error when the
+// project has none.
+func readSchema() (*schema, error) {
+
+	b, err := os.ReadFile(schemaFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var s schema
+	if err := yaml.UnmarshalStrict(b, &s); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", schemaFile, err)
+	}
+
+	return &s, nil
+}
+
+// schemaKeys returns the keys of s sorted.
+func (s *schema) schemaKeys() []string {
+
+	var keys []string
+	for key := range s.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// applyDefaults sets the keys of s that are unset or empty to their
+// defaults, then the computed keys.
+func (s *schema) applyDefaults() error {
+
+	for _, key := range s.schemaKeys() {
+		k := s.Keys[key]
+		if k.Default != "" && os.Getenv(key) == "" {
+			if err := setEnv(key, k.Default); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.applyComputed()
+}
+
+// applyComputed sets the computed keys of s to their value with the
+// references expanded, which may refer to other computed keys.
+func (s *schema) applyComputed() error {
+
+	done := make(map[string]bool)
+	computing := make(map[string]bool)
+
+	var compute func(key string) error
+	var nested error
+	lookup := func(name string) (string, bool) {
+		if s.Keys[name].Computed != "" && !done[name] && nested == nil {
+			nested = compute(name)
+		}
+		return os.LookupEnv(name)
+	}
+
+	compute = func(key string) error {
+
+		if computing[key] {
+			return fmt.Errorf("%s is computed from itself", key)
+		}
+		computing[key] = true
+
+		value, err := dotenv.Expand(s.Keys[key].Computed, lookup)
+		if err == nil {
+			err = nested
+		}
+		if err != nil {
+			return fmt.Errorf("can not compute %s: %v", key, err)
+		}
+
+		if old, ok := os.LookupEnv(key); ok && old != value && hasString(loadedKeys, key) {
+			warn("%s is computed in %s, ignoring its value in the dotenv file\n", key, schemaFile)
+		}
+		done[key] = true
+
+		return setEnv(key, value)
+	}
+
+	for _, key := range s.schemaKeys() {
+		if s.Keys[key].Computed == "" || done[key] {
+			continue
+		}
+		if err := compute(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// violations checks the environment against s and returns an error for
+// every key that does not conform, followed by the key's description in
+// descs.
+func (s *schema) violations(descs map[string]string) []*dotenv.ValidationError {
+
+	var violations []*dotenv.ValidationError
+	report := func(key string, msg message, data map[string]interface{}) {
+		v := &dotenv.ValidationError{Key: key, Message: tr(msg, data)}
+		if descs[key] != "" {
+			v.Message += " (" + descs[key] + ")"
+		}
+		violations = append(violations, v)
+	}
+
+	for _, key := range s.schemaKeys() {
+		k := s.Keys[key]
+
+		value := os.Getenv(key)
+		if value == "" {
+			if k.Required {
+				report(key, message{ID: "SchemaRequired", Other: "is required but not set"}, nil)
+			}
+			continue
+		}
+		quoted := map[string]interface{}{"Value": strconv.Quote(value)}
+
+		switch k.Type {
+		case "", "string":
+		case "int":
+			if _, err := strconv.Atoi(value); err != nil {
+				report(key, message{ID: "SchemaNotInt", Other: "{{.Value}} is not an integer"}, quoted)
+			}
+		case "bool":
+			if _, err := strconv.ParseBool(value); err != nil {
+				report(key, message{ID: "SchemaNotBool", Other: "{{.Value}} is not true or false"}, quoted)
+			}
+		case "url":
+			if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+				report(key, message{ID: "SchemaNotURL", Other: "{{.Value}} is not an absolute URL"}, quoted)
+			}
+		case "port":
+			if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+				report(key, message{ID: "SchemaNotPort", Other: "{{.Value}} is not a port between 1 and 65535"}, quoted)
+			}
+		case "enum":
+			if len(k.Enum) == 0 {
+				report(key, message{ID: "SchemaEnumEmpty", Other: "has type enum but the schema lists no values"}, nil)
+			}
+		case "json":
+			if _, err := decodeJSON(value); err != nil {
+				report(key, message{ID: "SchemaNotJSON", Other: "is not valid JSON: {{.Error}}"}, map[string]interface{}{"Error": err.Error()})
+			}
+		default:
+			report(key, message{ID: "SchemaUnknownType", Other: "unknown type {{.Type}} in {{.File}}, use {{.Types}}"},
+				map[string]interface{}{"Type": strconv.Quote(k.Type), "File": schemaFile, "Types": strings.Join(schemaTypes, ", ")})
+		}
+
+		if len(k.Enum) > 0 && !hasString(k.Enum, value) {
+			quoted["Values"] = strings.Join(k.Enum, ", ")
+			report(key, message{ID: "SchemaNotInEnum", Other: "{{.Value}} is not one of {{.Values}}"}, quoted)
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,261 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// NewSearchCmd returns the search command.
+func NewSearchCmd() *cobra.Command {
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Fuzzy search keys and values across the project's env files",
+		Long: `Search matches the query against the keys and values of every env file
+in the local directory (.env, .env.local, .env.example and so on) and
+prints the matches best first, with the file and line they are defined on.
+
+Secret values are masked and not searched unless --reveal is given.
+--copy puts the value of the best match on the clipboard, --edit opens
+the editor at its line.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := search(args[0]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	searchCmd.Flags().BoolVar(&flags.searchReveal, "reveal", false, "show and search secret values")
+	searchCmd.Flags().IntVarP(&flags.searchLimit, "limit", "n", 20, "maximum number of matches to print")
+	searchCmd.Flags().BoolVar(&flags.searchCopy, "copy", false, "copy the value of the best match to the clipboard")
+	searchCmd.Flags().BoolVar(&flags.searchEdit, "edit", false, "open the editor at the best match")
+
+	return searchCmd
+}
+
+// searchMatch is a variable matching the query, with where it is defined.
+type searchMatch struct {
+	file  string
+	line  int
+	v     envVar
+	score int
+}
+
+// search prints the variables matching query and runs the chosen action
+// on the best one.
+func search(query string) error {
+
+	files, err := envFiles()
+	if err != nil {
+		return err
+	}
+
+	d, err := selectedDialect()
+	if err != nil {
+		return err
+	}
+
+	var matches []searchMatch
+	for _, fname := range files {
+		f, err := os.Open(fname)
+		if err != nil {
+			return err
+		}
+		vars, err := parseEnvDialect(f, fname, d)
+		f.Close()
+		if err != nil {
+			info("skipping %v\n", err)
+			continue
+		}
+
+		lines, err := keyLines(fname)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range vars {
+			score, ok := fuzzyScore(query, v.Key)
+			if !isSecret(v.Key) || flags.searchReveal {
+				if s, vok := fuzzyScore(query, v.Value); vok && (!ok || s/2 > score) {
+					// value matches rank below key matches
+					score, ok = s/2, true
+				}
+			}
+			if ok {
+				matches = append(matches, searchMatch{file: fname, line: lines[v.Key], v: v, score: score})
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("nothing matches %q", query)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	for i, m := range matches {
+		if i == flags.searchLimit {
+			info("%d more match(es), use --limit to see them\n", len(matches)-i)
+			break
+		}
+
+		value := m.v.Value
+		if isSecret(m.v.Key) && !flags.searchReveal {
+			value = "********"
+		}
+		fmt.Printf("%s:%d\t%s=%s\n", m.file, m.line, m.v.Key, strings.Replace(value, "\n", `\n`, -1))
+	}
+
+	best := matches[0]
+
+	if flags.searchCopy {
+		if err := copyToClipboard(best.v.Value); err != nil {
+			return err
+		}
+		info("Copied the value of %s from %s:%d\n", best.v.Key, best.file, best.line)
+	}
+
+	if flags.searchEdit {
+		return openEditor(best.file, best.line)
+	}
+
+	return nil
+}
+
+// envFiles returns the env files in the local directory, the dotenv
+// file first.
+func envFiles() ([]string, error) {
+
+	files := []string{dotenvFileName()}
+	if _, err := os.Stat(dotenvFileName()); os.IsNotExist(err) {
+		files = nil
+	}
+
+	globbed, err := filepath.Glob(".env*")
+	if err != nil {
+		return nil, err
+	}
+	more, err := filepath.Glob("*.env")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fname := range append(globbed, more...) {
+		if fname == dotenvFileName() || strings.HasSuffix(fname, ".sig") {
+			continue
+		}
+		if fi, err := os.Stat(fname); err != nil || fi.IsDir() {
+			continue
+		}
+		files = append(files, fname)
+	}
+
+	return files, nil
+}
+
+// keyDefRe matches a line defining a variable, capturing its name.
+var keyDefRe = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*=`)
+
+// keyLines returns the line each key of fname is last defined on.
+func keyLines(fname string) (map[string]int, error) {
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make(map[string]int)
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if m := keyDefRe.FindStringSubmatch(scanner.Text()); m != nil {
+			lines[m[1]] = n
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// fuzzyScore reports whether the characters of query appear in s in
+// order, ignoring case, and how well they match. Substrings score highest,
+// then matches with the fewest gaps.
+func fuzzyScore(query, s string) (int, bool) {
+
+	q := strings.ToLower(query)
+	t := strings.ToLower(s)
+
+	if i := strings.Index(t, q); i >= 0 {
+		score := 1000 - i
+		if i == 0 {
+			score += 100
+		}
+		if len(t) == len(q) {
+			score += 100
+		}
+		return score, true
+	}
+
+	score := 500
+	next := 0
+	for _, r := range q {
+		i := strings.IndexRune(t[next:], r)
+		if i < 0 {
+			return 0, false
+		}
+		score -= i
+		next += i + utf8.RuneLen(r)
+	}
+
+	return score, true
+}
+
+// openEditor opens fname at line in $VISUAL or $EDITOR.
+func openEditor(fname string, line int) error {
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may be given with arguments, like "code -w"
+	args := strings.Fields(editor)
+	args = append(args, "+"+strconv.Itoa(line), fname)
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	return c.Run()
+}
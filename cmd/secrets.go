@@ -0,0 +1,30 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("secrets.patterns", []string{"*PASSWORD*", "*SECRET*", "*TOKEN*", "*_KEY", "*PRIVATE*"})
+}
+
+// isSecret reports whether the variable key holds a secret, going by the
+// glob patterns configured as secrets.patterns.
+func isSecret(key string) bool {
+	return matchesAny(strings.ToUpper(key), viper.GetStringSlice("secrets.patterns"))
+}
@@ -0,0 +1,258 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("compose.env_files", true)
+}
+
+// serviceEnvFile is a file listed under env_file by services of the
+// compose files.
+type serviceEnvFile struct {
+	path     string
+	services []string
+	required bool
+}
+
+// serviceEnvFiles returns the env_file entries of the compose files, each
+// file once with the services listing it. Paths are relative to the
+// compose file listing them.
+func serviceEnvFiles() ([]serviceEnvFile, error) {
+
+	entries, names, err := serviceEnvEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []serviceEnvFile
+	index := make(map[string]int)
+
+	for _, name := range names {
+		for _, e := range entries[name] {
+			if i, ok := index[e.path]; ok {
+				if !hasString(files[i].services, name) {
+					files[i].services = append(files[i].services, name)
+				}
+				files[i].required = files[i].required || e.required
+				continue
+			}
+			index[e.path] = len(files)
+			files = append(files, e)
+		}
+	}
+
+	return files, nil
+}
+
+// serviceEnvEntries returns the env_file entries of every service of the
+// compose files in the order compose reads them, and the names of the
+// services sorted. Compose files that can not be parsed are left to
+// compose to report.
+func serviceEnvEntries() (map[string][]serviceEnvFile, []string, error) {
+
+	entries := make(map[string][]serviceEnvFile)
+
+	for _, fname := range composeFiles() {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			continue
+		}
+
+		for name, service := range c.Services {
+			// a single path, a list of paths, or since compose 2.24 a list
+			// of {path, required}
+			var list []interface{}
+			switch v := service.EnvFile.(type) {
+			case string:
+				list = []interface{}{v}
+			case []interface{}:
+				list = v
+			}
+
+			for _, entry := range list {
+				path, required := "", true
+				switch e := entry.(type) {
+				case string:
+					path = e
+				case map[interface{}]interface{}:
+					path, _ = e["path"].(string)
+					if r, ok := e["required"].(bool); ok {
+						required = r
+					}
+				}
+				if path == "" {
+					return nil, nil, fmt.Errorf("%s: can not read env_file of %s", fname, name)
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(filepath.Dir(fname), path)
+				}
+
+				entries[name] = append(entries[name], serviceEnvFile{path: path, services: []string{name}, required: required})
+			}
+		}
+	}
+
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return entries, names, nil
+}
+
+// envFileValue is the value a service gets for a key from its env_file
+// entries, and the file it is from.
+type envFileValue struct {
+	value string
+	path  string
+}
+
+// loadServiceEnvFiles adds the variables of the services' env_file files
+// that the dotenv layers do not set, so loadenv resolves what the
+// containers get. Each service gets the values of its own entries, later
+// ones overriding earlier ones as in compose. Keys the layers set too are
+// left as they are, as are keys an earlier service, by name, got another
+// value for, with a warning when the values differ. origin maps the keys
+// loaded so far to their layer, and gets the new keys added.
+func loadServiceEnvFiles(layers []string, origin map[string]string) error {
+
+	if !viper.GetBool("compose.env_files") {
+		return nil
+	}
+
+	entries, names, err := serviceEnvEntries()
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]bool)
+	for _, layer := range layers {
+		if abs, err := filepath.Abs(layer); err == nil {
+			loaded[abs] = true
+		}
+	}
+
+	// env_file files are written for compose, whose syntax is posix like
+	d, err := dotenv.LookupDialect("posix")
+	if err != nil {
+		return err
+	}
+
+	// every file is read once, however many services list it
+	contents := make(map[string][]envVar)
+	absent := make(map[string]bool)
+	var missing []string
+	missingFor := make(map[string][]string)
+	read := func(e serviceEnvFile) ([]envVar, error) {
+
+		if vars, ok := contents[e.path]; ok {
+			return vars, nil
+		}
+		if abs, err := filepath.Abs(e.path); err == nil && loaded[abs] {
+			contents[e.path] = nil
+			return nil, nil
+		}
+
+		f, err := os.Open(e.path)
+		if os.IsNotExist(err) {
+			contents[e.path] = nil
+			absent[e.path] = true
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		vars, err := dotenv.Read(f, e.path, d, !flags.noExpand)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		contents[e.path] = vars
+
+		return vars, nil
+	}
+
+	imported := make(map[string]string)
+	warned := make(map[string]bool)
+	for _, name := range names {
+		env := make(map[string]envFileValue)
+		var keys []string
+		for _, e := range entries[name] {
+			vars, err := read(e)
+			if err != nil {
+				return err
+			}
+			if absent[e.path] && e.required {
+				if len(missingFor[e.path]) == 0 {
+					missing = append(missing, e.path)
+				}
+				missingFor[e.path] = append(missingFor[e.path], name)
+			}
+
+			for _, v := range vars {
+				if _, ok := env[v.Key]; !ok {
+					keys = append(keys, v.Key)
+				}
+				env[v.Key] = envFileValue{value: v.Value, path: e.path}
+			}
+		}
+
+		for _, key := range keys {
+			v := env[key]
+			from := fmt.Sprintf("%s (env_file of %s)", v.path, name)
+
+			first, ok := imported[key]
+			if !ok {
+				if layer, set := origin[key]; set {
+					if os.Getenv(key) != v.value && !warned[key] {
+						warn("%s is set differently in %s and %s, the container gets the env_file value unless its environment sets %s\n",
+							key, layer, from, key)
+						warned[key] = true
+					}
+					continue
+				}
+				if err := setEnv(key, v.value); err != nil {
+					return err
+				}
+				origin[key] = from
+				imported[key] = name
+				continue
+			}
+
+			// each container gets the value of its own env_file
+			if os.Getenv(key) != v.value && !warned[key] {
+				warn("%s is set differently in %s and %s, each container gets its own value but loadenv resolves the one of %s\n",
+					key, origin[key], from, first)
+				warned[key] = true
+			}
+		}
+	}
+
+	for _, path := range missing {
+		warn("%s, the env_file of %s, does not exist, compose will fail to start it\n", path, strings.Join(missingFor[path], ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// shellScript starts bash when the image has it and sh otherwise, so it
+// takes a single docker exec.
+const shellScript = `command -v bash >/dev/null 2>&1 && exec bash; exec sh`
+
+// NewShellCmd returns the shell command.
+func NewShellCmd() *cobra.Command {
+
+	shellCmd := &cobra.Command{
+		Use:   "shell [service]",
+		Short: "Open a shell in a running service with the loaded environment",
+		Long: `Shell opens an interactive shell in the container of a service of the
+running stack, bash when the image has it and sh otherwise, with the
+variables loadenv resolves added to its environment. The service defaults
+to app_service.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			service := viper.GetString("app_service")
+			if len(args) > 0 {
+				service = args[0]
+			}
+			if err := execService(service, []string{"sh", "-c", shellScript}); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exit(exitErr.ExitCode())
+				}
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	shellCmd.Flags().StringVarP(&flags.execUser, "user", "u", "", "user to run the shell as")
+	shellCmd.Flags().StringVarP(&flags.execWorkdir, "workdir", "w", "", "directory to start the shell in")
+
+	return shellCmd
+}
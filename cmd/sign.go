@@ -0,0 +1,169 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// signatureNamespace scopes loadenv signatures so they can not be
+// confused with signatures made for other purposes with the same key.
+const signatureNamespace = "loadenv"
+
+// NewSignCmd returns the sign command.
+func NewSignCmd() *cobra.Command {
+
+	signCmd := &cobra.Command{
+		Use:   "sign [file]",
+		Short: "Sign a dotenv file with an ssh key",
+		Long: `Sign writes a detached signature for the dotenv file to <file>.sig using
+ssh-keygen -Y sign. Files are checked against it when loading with
+--verify-signatures or signatures.verify, which checks every file the
+environment is loaded from, the layers and the env_file imports too, so
+each of them has to be signed.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			fname := dotenvFileName()
+			if len(args) > 0 {
+				fname = args[0]
+			}
+
+			if err := sign(fname); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	signCmd.Flags().StringVar(&flags.signKey, "key", "", "private ssh key to sign with (default is signatures.key from config)")
+
+	return signCmd
+}
+
+// sign writes a detached signature for fname.
+func sign(fname string) error {
+
+	if err := checkReadOnly("sign"); err != nil {
+		return err
+	}
+
+	key := flags.signKey
+	if key == "" {
+		key = viper.GetString("signatures.key")
+	}
+	if key == "" {
+		return fmt.Errorf("no signing key given, use --key or set signatures.key in the config")
+	}
+
+	key, err := homedir.Expand(key)
+	if err != nil {
+		return err
+	}
+
+	// ssh-keygen refuses to overwrite an existing signature
+	if err := os.Remove(fname + ".sig"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	sshKeygenCmd := exec.Command("ssh-keygen", "-Y", "sign", "-f", key, "-n", signatureNamespace, fname)
+	sshKeygenCmd.Stdin = os.Stdin
+	sshKeygenCmd.Stdout = os.Stdout
+	sshKeygenCmd.Stderr = os.Stderr
+
+	return sshKeygenCmd.Run()
+}
+
+// signaturesRequired reports whether the dotenv files must be signed, with
+// --verify-signatures or the signatures.verify config.
+func signaturesRequired() bool {
+	return flags.verifySigs || viper.GetBool("signatures.verify")
+}
+
+// verifyLayers checks the signature of every layer and every env_file
+// import that exists before any of them is loaded, as each of them sets
+// variables. Missing files are left to the loader to report.
+func verifyLayers(layers []string) error {
+
+	fnames := append([]string{}, layers...)
+	if viper.GetBool("compose.env_files") {
+		files, err := serviceEnvFiles()
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			fnames = append(fnames, file.path)
+		}
+	}
+
+	for _, fname := range fnames {
+		if _, err := os.Stat(fname); os.IsNotExist(err) {
+			continue
+		}
+		if err := verifySignature(fname); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifySignature checks fname against its detached signature using the
+// allowed signers file configured as signatures.allowed_signers.
+func verifySignature(fname string) error {
+
+	signers := viper.GetString("signatures.allowed_signers")
+	if signers == "" {
+		return fmt.Errorf("signatures.allowed_signers must be set in the config to verify signatures")
+	}
+
+	signers, err := homedir.Expand(signers)
+	if err != nil {
+		return err
+	}
+
+	sig := fname + ".sig"
+	if _, err := os.Stat(sig); os.IsNotExist(err) {
+		return fmt.Errorf("can not find signature %s for %s", sig, fname)
+	}
+
+	out, err := exec.Command("ssh-keygen", "-Y", "find-principals", "-f", signers, "-s", sig).Output()
+	if err != nil {
+		return fmt.Errorf("%s is not signed by an allowed signer", fname)
+	}
+	principal := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stderr bytes.Buffer
+	verifyCmd := exec.Command("ssh-keygen", "-Y", "verify", "-f", signers, "-I", principal, "-n", signatureNamespace, "-s", sig)
+	verifyCmd.Stdin = f
+	verifyCmd.Stderr = &stderr
+	if err := verifyCmd.Run(); err != nil {
+		return fmt.Errorf("signature verification of %s failed: %s", fname, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
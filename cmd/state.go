@@ -0,0 +1,182 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stateFile records what loadenv started in the project so it can be
+// torn down exactly, even after a crash or a reboot.
+const stateFile = ".loadenv/state.json"
+
+// state is the content of the state file.
+type state struct {
+	Project     string    `json:"project"`
+	ProjectPath string    `json:"project_path,omitempty"`
+	GitRemote   string    `json:"git_remote,omitempty"`
+	Services    []string  `json:"services"`
+	Overrides   []string  `json:"overrides"`
+	TempFiles   []string  `json:"temp_files"`
+	StartedAt   time.Time `json:"started_at"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	EnvHash     string    `json:"env_hash,omitempty"`
+}
+
+// readState reads the state file. It returns nil and no error when
+// loadenv has not started anything in the project.
+func readState() (*state, error) {
+
+	b, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+
+	return &st, nil
+}
+
+// writeState atomically replaces the state file with st.
+func writeState(st *state) error {
+
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return err
+	}
+
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, stateFile)
+}
+
+// recordState writes the state for the stack about to be started.
+func recordState() error {
+
+	st := &state{
+		Project:   stackProject(),
+		GitRemote: gitRemote(),
+		Overrides: overrideFiles,
+		TempFiles: overrideFiles,
+		StartedAt: time.Now(),
+	}
+	if wd, err := os.Getwd(); err == nil {
+		st.ProjectPath, _ = realPath(wd)
+	}
+	if flags.stackTTL > 0 {
+		st.ExpiresAt = st.StartedAt.Add(flags.stackTTL)
+	}
+
+	st.Services = stackServices()
+
+	var err error
+	if st.EnvHash, err = loadedEnvHash(); err != nil {
+		return err
+	}
+
+	return writeState(st)
+}
+
+// loadedEnvHash returns the envHash of the loaded variables, with the
+// values the stack is started with.
+func loadedEnvHash() (string, error) {
+
+	var vars []envVar
+	for _, key := range loadedKeys {
+		vars = append(vars, envVar{Key: key, Value: os.Getenv(key)})
+	}
+
+	return envHash(vars)
+}
+
+// attachState points compose at the stack recorded in the state file,
+// which may have been started by an earlier loadenv process, and returns
+// the state or nil when nothing was started.
+func attachState() (*state, error) {
+
+	st, err := readState()
+	if err != nil || st == nil {
+		return st, err
+	}
+
+	composeProject = st.Project
+	overrideFiles = nil
+	for _, fname := range st.Overrides {
+		if _, err := os.Stat(fname); err == nil {
+			overrideFiles = append(overrideFiles, fname)
+		}
+	}
+
+	return st, nil
+}
+
+// stackServices returns the sorted names of the services defined by the
+// project's compose files and the generated overrides.
+func stackServices() []string {
+
+	seen := make(map[string]bool)
+	for _, fname := range append(composeFiles(), overrideFiles...) {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			continue
+		}
+		for name := range c.Services {
+			seen[name] = true
+		}
+	}
+
+	var services []string
+	for name := range seen {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+var projectNameRe = regexp.MustCompile(`[^-_a-z0-9]`)
+
+// projectName returns the compose project name docker-compose uses for
+// the current directory.
+func projectName() string {
+
+	if name := os.Getenv("COMPOSE_PROJECT_NAME"); name != "" {
+		return name
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	return projectNameRe.ReplaceAllString(strings.ToLower(filepath.Base(dir)), "")
+}
@@ -0,0 +1,254 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// serviceStatus is the state of one container of the stack.
+type serviceStatus struct {
+	Service   string     `json:"service"`
+	Container string     `json:"container,omitempty"`
+	State     string     `json:"state"`
+	Health    string     `json:"health,omitempty"`
+	Ports     []string   `json:"ports,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Drift     string     `json:"drift,omitempty"`
+
+	// envHash is the env-hash label of the container
+	envHash string
+	// exitCode is the exit code of an exited container
+	exitCode int
+}
+
+// NewStatusCmd returns the status command.
+func NewStatusCmd() *cobra.Command {
+
+	statusCmd := &cobra.Command{
+		Use:     "status",
+		Aliases: []string{"ps"},
+		Short:   "List the stack's services with the state of their containers",
+		Long: `Status lists the containers of the stack with their state, e.g. running,
+paused or exited, their health check status, the ports published on the
+host and how long they have been up. Services loadenv started that have no
+container are listed as not created.
+
+With --drift the dotenv file is read, and each container is listed as
+changed when it was created with other values than the file now resolves
+to, so it needs to be recreated, or unchanged otherwise.
+
+With --json the list is printed as JSON for scripts.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := printStatus(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	statusCmd.Flags().BoolVar(&flags.statusJSON, "json", false, "print the status as JSON")
+	statusCmd.Flags().BoolVar(&flags.statusDrift, "drift", false, "compare the containers' environment with the dotenv file")
+
+	return statusCmd
+}
+
+// stackStatus returns the status of the stack's containers, ordered by
+// service, followed by the recorded services without a container.
+func stackStatus() ([]serviceStatus, error) {
+
+	st, err := attachState()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := dockerLines("ps", "--all", "--filter", "label=com.docker.compose.project="+stackProject(),
+		"--format", `{{.ID}}	{{.Label "com.docker.compose.service"}}	{{.State}}	{{.Ports}}`)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []serviceStatus
+	var ids []string
+	for _, line := range lines {
+		parts := strings.SplitN(line, "\t", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		statuses = append(statuses, serviceStatus{
+			Service:   parts[1],
+			Container: parts[0],
+			State:     parts[2],
+			Ports:     publishedPorts(parts[3]),
+		})
+		ids = append(ids, parts[0])
+	}
+
+	if len(ids) > 0 {
+		// ps has neither the health status nor the start time on its own
+		lines, err := dockerLines(append([]string{"inspect", "--format",
+			`{{.Id}}	{{if .State.Health}}{{.State.Health.Status}}{{end}}	{{.State.StartedAt}}	{{.State.ExitCode}}	{{index .Config.Labels "` + envHashLabel + `"}}`}, ids...)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			parts := strings.SplitN(line, "\t", 5)
+			if len(parts) < 5 {
+				continue
+			}
+			for i := range statuses {
+				if !strings.HasPrefix(parts[0], statuses[i].Container) {
+					continue
+				}
+				statuses[i].Health = parts[1]
+				// containers that never ran started at year 1
+				if t, err := time.Parse(time.RFC3339Nano, parts[2]); err == nil && t.Year() > 1 {
+					statuses[i].StartedAt = &t
+				}
+				statuses[i].exitCode, _ = strconv.Atoi(parts[3])
+				statuses[i].envHash = parts[4]
+			}
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Service < statuses[j].Service })
+
+	if st != nil {
+		for _, name := range st.Services {
+			found := false
+			for _, s := range statuses {
+				found = found || s.Service == name
+			}
+			if !found {
+				statuses = append(statuses, serviceStatus{Service: name, State: "not created"})
+			}
+		}
+	}
+
+	return statuses, nil
+}
+
+// publishedPorts returns the ports of a docker ps Ports column that are
+// published on the host, e.g. 8080->80/tcp, once for IPv4 and IPv6.
+func publishedPorts(ports string) []string {
+
+	var published []string
+	for _, port := range strings.Split(ports, ", ") {
+		mapping := strings.SplitN(port, "->", 2)
+		if len(mapping) < 2 {
+			continue
+		}
+		p := mapping[0][strings.LastIndex(mapping[0], ":")+1:] + "->" + mapping[1]
+		if !hasString(published, p) {
+			published = append(published, p)
+		}
+	}
+
+	return published
+}
+
+// printStatus prints the status of the stack as a table or as JSON.
+func printStatus() error {
+
+	statuses, err := stackStatus()
+	if err != nil {
+		return err
+	}
+
+	if flags.statusDrift {
+		if err := setDrift(statuses); err != nil {
+			return err
+		}
+	}
+
+	if flags.statusJSON {
+		if statuses == nil {
+			statuses = []serviceStatus{}
+		}
+		// keep the -> of the ports readable
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	if len(statuses) == 0 {
+		info("No containers of %s\n", stackProject())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := "SERVICE\tSTATE\tHEALTH\tPORTS\tUPTIME"
+	if flags.statusDrift {
+		header += "\tENV"
+	}
+	fmt.Fprintln(w, header)
+	for _, s := range statuses {
+		health, ports, uptime := "-", "-", "-"
+		if s.Health != "" {
+			health = s.Health
+		}
+		if len(s.Ports) > 0 {
+			ports = strings.Join(s.Ports, ", ")
+		}
+		if (s.State == "running" || s.State == "paused") && s.StartedAt != nil {
+			uptime = time.Since(*s.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s", s.Service, s.State, health, ports, uptime)
+		if flags.statusDrift {
+			drift := "-"
+			if s.Drift != "" {
+				drift = s.Drift
+			}
+			fmt.Fprintf(w, "\t%s", drift)
+		}
+		fmt.Fprintln(w)
+	}
+
+	return w.Flush()
+}
+
+// setDrift sets the Drift of the containers in statuses by comparing their
+// env-hash label with the hash of the variables the dotenv file resolves
+// to. Containers without the label, e.g. created before loadenv labelled
+// them, are left without one.
+func setDrift(statuses []serviceStatus) error {
+
+	h, err := currentEnvHash()
+	if err != nil {
+		return err
+	}
+
+	for i := range statuses {
+		switch statuses[i].envHash {
+		case "":
+		case h:
+			statuses[i].Drift = "unchanged"
+		default:
+			statuses[i].Drift = "changed"
+		}
+	}
+
+	return nil
+}
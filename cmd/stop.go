@@ -0,0 +1,125 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// errStopped is returned by a stopper that was stopped by a signal.
+var errStopped = errors.New("stopped by a signal")
+
+// stopper cancels its context when loadenv receives SIGINT or SIGTERM, so
+// no further steps are started, and passes the signals on to the command
+// it runs.
+type stopper struct {
+	ctx  context.Context
+	sigs chan os.Signal
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+// newStopper starts handling SIGINT and SIGTERM until release is called.
+func newStopper() *stopper {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &stopper{ctx: ctx, sigs: make(chan os.Signal, 1)}
+	signal.Notify(s.sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for sig := range s.sigs {
+			cancel()
+
+			// a Ctrl-C in the terminal reaches the whole foreground
+			// process group, compose included, so only signals sent to
+			// loadenv alone are forwarded. Compose stops the containers
+			// gracefully on the first and kills them on the second.
+			if sig == os.Interrupt {
+				continue
+			}
+			s.mu.Lock()
+			if s.cmd != nil {
+				if err := s.cmd.Process.Signal(sig); err != nil {
+					s.cmd.Process.Kill()
+				}
+			}
+			s.mu.Unlock()
+		}
+	}()
+
+	return s
+}
+
+// release restores the default handling of the signals.
+func (s *stopper) release() {
+
+	signal.Stop(s.sigs)
+	close(s.sigs)
+}
+
+// stopped reports whether a signal was received.
+func (s *stopper) stopped() bool {
+	return s.ctx.Err() != nil
+}
+
+// run runs c like runDocker, within the timeout when timed, and waits for
+// it to exit, even when a signal is received. It returns errStopped
+// instead of starting c once stopped, and when c exits after a signal.
+// Untimed commands, an attached up, are not retried like runAttached.
+func (s *stopper) run(c *exec.Cmd, timed bool) error {
+
+	if !timed {
+		_, err := runDockerOnce(c, false, s.runOnce)
+		return err
+	}
+
+	return retryDocker(c, true, s.runOnce)
+}
+
+// runOnce runs c for run.
+func (s *stopper) runOnce(c *exec.Cmd) error {
+
+	s.mu.Lock()
+	if s.stopped() {
+		s.mu.Unlock()
+		return errStopped
+	}
+	err := c.Start()
+	if err == nil {
+		s.cmd = c
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = c.Wait()
+
+	s.mu.Lock()
+	s.cmd = nil
+	s.mu.Unlock()
+
+	if s.stopped() {
+		return errStopped
+	}
+
+	return err
+}
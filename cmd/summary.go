@@ -0,0 +1,172 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	warningCount int
+	suggestions  []string
+)
+
+// httpPorts are the container ports whose published host ports are listed
+// as URLs in the summary.
+var httpPorts = map[string]string{
+	"80":   "http",
+	"443":  "https",
+	"3000": "http",
+	"5173": "http",
+	"8000": "http",
+	"8080": "http",
+}
+
+// warn prints a warning and counts it for the summary.
+func warn(format string, args ...interface{}) {
+
+	warningCount++
+	fmt.Fprintf(os.Stderr, "warning: "+format, args...)
+}
+
+// suggest adds a suggestion to the summary, once.
+func suggest(format string, args ...interface{}) {
+
+	s := fmt.Sprintf(format, args...)
+	for _, seen := range suggestions {
+		if seen == s {
+			return
+		}
+	}
+
+	suggestions = append(suggestions, s)
+}
+
+// suggestExample suggests running check when the dotenv file and
+// .env.example disagree.
+func suggestExample() {
+
+	if _, err := os.Stat(".env.example"); err != nil {
+		return
+	}
+	if _, err := os.Stat(dotenvFileName()); err != nil {
+		return
+	}
+
+	missing, extra, empty, err := compareExample(".env.example")
+	if err != nil {
+		return
+	}
+
+	if len(missing) > 0 {
+		suggest("%s", tr(message{
+			ID:    "SummaryMissingVars",
+			One:   "{{.Count}} variable missing from {{.File}} — run loadenv check",
+			Other: "{{.Count}} variables missing from {{.File}} — run loadenv check",
+		}, map[string]interface{}{"Count": len(missing), "File": dotenvFileName()}))
+	}
+	if len(extra) > 0 {
+		suggest("%s", tr(message{
+			ID:    "SummaryExtraVars",
+			One:   "{{.Count}} variable missing from .env.example — run loadenv example",
+			Other: "{{.Count}} variables missing from .env.example — run loadenv example",
+		}, map[string]interface{}{"Count": len(extra)}))
+	}
+	if len(empty) > 0 {
+		suggest("%s", tr(message{
+			ID:    "SummaryEmptyVars",
+			One:   "{{.Count}} variable required but empty in {{.File}} — run loadenv check",
+			Other: "{{.Count}} variables required but empty in {{.File}} — run loadenv check",
+		}, map[string]interface{}{"Count": len(empty), "File": dotenvFileName()}))
+	}
+}
+
+// runningStack returns the running services of the stack and the URLs of
+// their published http ports.
+func runningStack() ([]string, []string) {
+
+	lines, err := dockerLines("ps", "--filter", "label=com.docker.compose.project="+stackProject(),
+		"--format", `{{.Label "com.docker.compose.service"}}	{{.Ports}}`)
+	if err != nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var services, urls []string
+	for _, line := range lines {
+		parts := strings.SplitN(line, "\t", 2)
+		if !seen[parts[0]] {
+			seen[parts[0]] = true
+			services = append(services, parts[0])
+		}
+		if len(parts) < 2 {
+			continue
+		}
+
+		// e.g. 0.0.0.0:8080->80/tcp, :::8080->80/tcp, 3306/tcp
+		for _, port := range strings.Split(parts[1], ", ") {
+			mapping := strings.SplitN(port, "->", 2)
+			if len(mapping) < 2 {
+				continue
+			}
+			scheme, ok := httpPorts[strings.TrimSuffix(mapping[1], "/tcp")]
+			if !ok {
+				continue
+			}
+			host := mapping[0][strings.LastIndex(mapping[0], ":")+1:]
+			u := fmt.Sprintf("%s://localhost:%s", scheme, host)
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	sort.Strings(services)
+	sort.Strings(urls)
+
+	return services, urls
+}
+
+// printSummary prints the summary block ending up, validate and doctor,
+// unless the summary config is false. up passes the stack's services and
+// URLs, the other commands nil.
+func printSummary(services, urls []string) {
+
+	if viper.IsSet("summary") && !viper.GetBool("summary") {
+		return
+	}
+
+	suggestExample()
+
+	info("\n%s\n", tr(message{ID: "SummaryTitle", Other: "Summary:"}, nil))
+	if services != nil {
+		info("  %-10s %s\n", tr(message{ID: "SummaryServices", Other: "services"}, nil), strings.Join(services, ", "))
+	}
+	if len(urls) > 0 {
+		info("  %-10s %s\n", tr(message{ID: "SummaryURLs", Other: "urls"}, nil), strings.Join(urls, ", "))
+	}
+	info("  %-10s %d\n", tr(message{ID: "SummaryWarnings", Other: "warnings"}, nil), warningCount)
+
+	if len(suggestions) > 0 {
+		info("%s\n", tr(message{ID: "SummarySuggestions", Other: "Suggestions:"}, nil))
+		for _, s := range suggestions {
+			info("  - %s\n", s)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("sync.image", "alpine:3")
+	viper.SetDefault("sync.interval", 1)
+}
+
+// syncConfig configures file sync for a single service.
+type syncConfig struct {
+	Path    string   `mapstructure:"path"`
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// setupSync replaces the source bind mount of every service configured
+// under sync.services with a named volume, kept up to date by a sidecar
+// running an rsync loop. Services then read their code from a fast
+// volume instead of a slow bind mount (e.g. osxfs on macOS).
+func setupSync() error {
+
+	var services map[string]syncConfig
+	if err := viper.UnmarshalKey("sync.services", &services); err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("--sync needs at least one service configured under sync.services")
+	}
+
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	overrides := make(map[string]interface{})
+	volumes := make(map[string]interface{})
+
+	for _, name := range names {
+		cfg := services[name]
+		if cfg.Path == "" {
+			return fmt.Errorf("sync.services.%s.path must be set", name)
+		}
+
+		volume := "loadenv_sync_" + name
+		volumes[volume] = map[string]interface{}{}
+
+		// compose merges volumes by container path, so this replaces
+		// the service's bind mount of the same path
+		overrides[name] = map[string]interface{}{
+			"volumes": []string{volume + ":" + cfg.Path},
+		}
+
+		args := []string{"rsync", "-a", "--delete"}
+		for _, p := range cfg.Include {
+			args = append(args, shellQuote("--include="+p))
+		}
+		for _, p := range cfg.Exclude {
+			args = append(args, shellQuote("--exclude="+p))
+		}
+		args = append(args, "/src/", "/dst/")
+
+		script := fmt.Sprintf("apk add --no-cache rsync >/dev/null && while true; do %s; sleep %d; done",
+			strings.Join(args, " "), viper.GetInt("sync.interval"))
+
+		overrides[name+"-sync"] = map[string]interface{}{
+			"image":   viper.GetString("sync.image"),
+			"command": []string{"sh", "-c", script},
+			"volumes": []string{".:/src:ro", volume + ":/dst"},
+		}
+	}
+
+	return writeOverride("docker-compose.sync.yml", map[string]interface{}{
+		"services": overrides,
+		"volumes":  volumes,
+	})
+}
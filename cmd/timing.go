@@ -0,0 +1,131 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// phaseTime is how long a named phase of a command took.
+type phaseTime struct {
+	name     string
+	duration time.Duration
+}
+
+var (
+	commandStart time.Time
+	phaseTimes   []phaseTime
+)
+
+// phaseHint is advice printed when a phase takes longer than threshold.
+// The threshold can be overridden with the timing.thresholds.<phase>
+// config.
+type phaseHint struct {
+	threshold time.Duration
+	hint      string
+}
+
+var phaseHints = map[string]phaseHint{
+	"build": {
+		threshold: 2 * time.Minute,
+		hint:      "enable BuildKit with DOCKER_BUILDKIT=1 and COMPOSE_DOCKER_CLI_BUILD=1 so unchanged layers are cached between builds",
+	},
+	"scan": {
+		threshold: time.Minute,
+		hint:      "unset scan.before_up and run loadenv scan only when the images change",
+	},
+	"sync": {
+		threshold: time.Minute,
+		hint:      "add large directories such as vendor/ and node_modules/ to .dockerignore so they are not synced",
+	},
+	"policies": {
+		threshold: 10 * time.Second,
+		hint:      "trim policy.files to the policies this project needs",
+	},
+	"command": {
+		threshold: 5 * time.Minute,
+		hint:      "run with --timings to see which phases are slow",
+	},
+}
+
+// foregroundPhases run the user's own processes, such as the attached
+// stack, so their time is not counted against the command.
+var foregroundPhases = map[string]bool{"up": true, "run": true}
+
+// timePhase runs fn and records how long it took under name.
+func timePhase(name string, fn func() error) error {
+
+	start := time.Now()
+	err := fn()
+	phaseTimes = append(phaseTimes, phaseTime{name: name, duration: time.Since(start)})
+
+	return err
+}
+
+// startTiming marks the start of the command.
+func startTiming(cmd *cobra.Command, args []string) {
+
+	commandStart = time.Now()
+	phaseTimes = nil
+}
+
+// reportTimings prints the phase durations when --timings is given, and
+// hints for every phase that exceeded its threshold.
+func reportTimings(cmd *cobra.Command, args []string) {
+
+	elapsed := time.Since(commandStart)
+
+	if flags.showTimings {
+		for _, p := range phaseTimes {
+			info("%-10s %s\n", p.name, p.duration.Round(time.Millisecond))
+		}
+		info("%s finished in %s\n", cmd.CommandPath(), elapsed.Round(time.Millisecond))
+	}
+
+	total := phaseTime{name: "command", duration: elapsed}
+	for _, p := range phaseTimes {
+		if foregroundPhases[p.name] {
+			total.duration -= p.duration
+		}
+	}
+
+	if viper.IsSet("timing.hints") && !viper.GetBool("timing.hints") {
+		return
+	}
+
+	hinted := false
+	for _, p := range append(phaseTimes, total) {
+		h, ok := phaseHints[p.name]
+		if !ok || (p == total && hinted) {
+			continue
+		}
+
+		threshold := h.threshold
+		if key := "timing.thresholds." + p.name; viper.IsSet(key) {
+			threshold = viper.GetDuration(key)
+		}
+
+		if p.duration > threshold {
+			name := p.name
+			if p == total {
+				name = cmd.CommandPath()
+			}
+			info("hint: %s took %s — %s\n", name, p.duration.Round(time.Second), h.hint)
+			hinted = true
+		}
+	}
+}
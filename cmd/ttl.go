@@ -0,0 +1,63 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// expiringCommands are the commands that stop an expired stack first. The
+// others, e.g. export or hook, print what the shell evaluates and must
+// not take the stack down on the way.
+var expiringCommands = map[string]bool{
+	"up":     true,
+	"run":    true,
+	"status": true,
+}
+
+// stopExpired tears down the project's stack when it was started with
+// --ttl and has outlived it, for stacks left running detached or by a
+// loadenv process that was killed before its timer fired. Only the bare
+// command and the expiringCommands do so, and everything they print then
+// goes to stderr.
+func stopExpired(cmd *cobra.Command) {
+
+	if cmd.HasParent() && (cmd.Parent().HasParent() || !expiringCommands[cmd.Name()]) {
+		return
+	}
+
+	st, err := readState()
+	if err != nil || st == nil || st.ExpiresAt.IsZero() || time.Now().Before(st.ExpiresAt) {
+		return
+	}
+
+	info("The stack expired at %s, stopping it\n", st.ExpiresAt.Format(time.Kitchen))
+
+	if _, err := attachState(); err != nil {
+		warn("can not stop the expired stack: %v\n", err)
+		return
+	}
+	c := composeCommand("down")
+	c.Stdout = os.Stderr
+	if err := runDocker(c); err != nil {
+		warn("can not stop the expired stack: %v\n", err)
+		return
+	}
+	if err := cleanup(); err != nil {
+		warn("can not clean up after the expired stack: %v\n", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewUnusedCmd returns the unused command.
+func NewUnusedCmd() *cobra.Command {
+
+	unusedCmd := &cobra.Command{
+		Use:   "unused",
+		Short: "List dotenv variables the application does not use",
+		Long: `Unused lists the variables of the dotenv file the application never reads.
+With --from-runtime the keys recorded by the helper from loadenv instrument
+php are used, otherwise the project's PHP files are searched for the keys.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := unused(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	unusedCmd.Flags().StringVar(&flags.unusedFromRuntime, "from-runtime", "", "keys log written by the instrumented app")
+
+	return unusedCmd
+}
+
+// unused prints the unused variables of the dotenv file.
+func unused() error {
+
+	vars, err := parseEnvLayers(dotenvFileName())
+	if err != nil {
+		return err
+	}
+
+	var used map[string]bool
+	if flags.unusedFromRuntime != "" {
+		used, err = runtimeKeys(flags.unusedFromRuntime)
+	} else {
+		used, err = referencedKeys(vars)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, v := range vars {
+		if !used[v.Key] {
+			fmt.Println(v.Key)
+		}
+	}
+
+	return nil
+}
+
+// runtimeKeys reads the keys log written by the PHP instrument.
+func runtimeKeys(fname string) (map[string]bool, error) {
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var log struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &log); err != nil {
+		return nil, fmt.Errorf("can not parse %s: %v", fname, err)
+	}
+
+	used := make(map[string]bool)
+	for _, key := range log.Keys {
+		used[key] = true
+	}
+
+	return used, nil
+}
+
+// referencedKeys searches the project's PHP files for the variable names.
+func referencedKeys(vars []envVar) (map[string]bool, error) {
+
+	used := make(map[string]bool)
+
+	err := filepath.Walk(".", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			switch fi.Name() {
+			case "vendor", "node_modules", ".git", ".loadenv":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".php") {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range vars {
+			if !used[v.Key] && strings.Contains(string(b), v.Key) {
+				used[v.Key] = true
+			}
+		}
+
+		return nil
+	})
+
+	return used, err
+}
@@ -0,0 +1,156 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// upPassthrough are the arguments given after -- for compose up.
+var upPassthrough []string
+
+// NewUpCmd returns the up command.
+func NewUpCmd() *cobra.Command {
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Load the environment, build and start the stack",
+		Long: `Up loads the dotenv file into the environment, builds the images and
+starts the stack with docker-compose up, attached unless -d is given.
+Running loadenv without a subcommand is the same as loadenv up.
+
+With -e staging the variables are loaded from .env, .env.local,
+.env.staging and .env.staging.local in that order, later files overriding
+earlier ones. Missing files other than .env are skipped.
+
+The services with a build section need their Dockerfile in the build
+context, unless --no-docker-check is given. Stacks of prebuilt images only
+need the compose file, and are never built.
+
+Images are built every time unless --no-build is given or the up.build
+config is false; --build builds regardless of the config.
+
+Arguments after -- are passed on to compose up, e.g.
+loadenv up -- --force-recreate app.
+
+Ctrl-C or SIGTERM stop an attached stack cleanly: loadenv waits for
+compose to stop the containers and, with --down-on-stop or the
+up.down_on_stop config, removes them with down.
+
+With --wait, for CI pipelines, the stack is started in the background and
+up waits until every container is running and healthy if it has a health
+check. Containers without one must listen on their published TCP ports,
+which is checked in the container, as docker accepts connections on the
+host port before the service does. One-shot containers, e.g. migrations,
+are done once they exit with 0. Services still not ready after
+--wait-timeout, or the up.wait_timeout config of 2m by default, are listed
+and up exits with a non-zero status.
+
+With -d it ends with a summary of the running services, their URLs, the
+number of warnings and suggestions, unless the summary config is false.`,
+		Args: passthroughArgs(&upPassthrough),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := load(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(1)
+			}
+		},
+	}
+
+	addUpFlags(upCmd.Flags())
+	upCmd.Flags().BoolVarP(&flags.upDetach, "detach", "d", false, "start the stack in the background")
+	upCmd.Flags().BoolVar(&flags.upWait, "wait", false, "start the stack in the background and wait until its services are ready")
+	upCmd.Flags().DurationVar(&flags.upWaitTimeout, "wait-timeout", 0, "how long --wait waits for the services, e.g. 5m")
+	upCmd.Flags().DurationVar(&flags.upWaitProbeTimeout, "wait-probe-timeout", 0, "how long --wait waits for a connection to a published port (default is up.wait_probe_timeout, 2s)")
+	upCmd.Flags().BoolVar(&flags.upBuild, "build", false, "build the images before starting the stack")
+	upCmd.Flags().BoolVar(&flags.upNoBuild, "no-build", false, "start the stack without building the images")
+
+	return upCmd
+}
+
+// addUpFlags adds the flags shared by up and the bare root command.
+func addUpFlags(fs *pflag.FlagSet) {
+
+	fs.BoolVar(&flags.verifySigs, "verify-signatures", false, "reject dotenv files whose signature does not verify")
+	fs.BoolVar(&flags.withNode, "node", false, "add a node service running the vite/mix dev server")
+	fs.StringVar(&flags.perfMode, "perf", "", "php performance mode for the app service (dev|debug|profile|prod-like)")
+	fs.BoolVar(&flags.scanBeforeUp, "scan", false, "scan the stack's images for vulnerabilities before starting it")
+	fs.BoolVar(&flags.withSync, "sync", false, "sync source code into named volumes instead of bind mounts")
+	fs.StringVar(&flags.remoteHost, "remote", "", "run the stack on user@host over ssh")
+	fs.DurationVar(&flags.stackTTL, "ttl", 0, "stop the stack after this long, e.g. 4h")
+	fs.BoolVar(&flags.userSuffix, "user-suffix", false, "namespace project and host ports by the invoking user")
+	fs.BoolVar(&flags.validateOnUp, "validate", false, "check the environment against "+schemaFile+" before starting the stack")
+	fs.BoolVar(&flags.upDownOnStop, "down-on-stop", false, "remove the stack when it is stopped with Ctrl-C or SIGTERM")
+	fs.BoolVar(&flags.noDockerCheck, "no-docker-check", false, "start the stack without checking for a Dockerfile")
+}
+
+// buildImages reports whether the images should be built before the
+// stack is started. Stacks of prebuilt images are never built.
+func buildImages() (bool, error) {
+
+	if flags.upBuild && flags.upNoBuild {
+		return false, fmt.Errorf("can not use --build and --no-build together")
+	}
+
+	if flags.upBuild {
+		return buildsImages(), nil
+	}
+	if flags.upNoBuild {
+		return false, nil
+	}
+	if viper.IsSet("up.build") && !viper.GetBool("up.build") {
+		return false, nil
+	}
+
+	return buildsImages(), nil
+}
+
+// passthroughArgs returns a cobra.PositionalArgs accepting only the
+// arguments after --, which it stores in dst to be passed on to compose.
+func passthroughArgs(dst *[]string) cobra.PositionalArgs {
+
+	return func(cmd *cobra.Command, args []string) error {
+
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 {
+			dash = len(args)
+		}
+		if dash > 0 && cmd.HasSubCommands() {
+			return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+		}
+		if dash > 0 {
+			return fmt.Errorf("unexpected argument %q, put compose arguments after --", args[0])
+		}
+
+		*dst = args[dash:]
+
+		return nil
+	}
+}
+
+// upArgs returns the docker-compose up arguments for the stack.
+func upArgs() []string {
+
+	args := []string{"up"}
+	if flags.upDetach {
+		args = append(args, "-d")
+	}
+
+	return append(args, upPassthrough...)
+}
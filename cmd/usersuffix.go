@@ -0,0 +1,89 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	// Only host-side ports are offset; variables like DB_PORT are used
+	// inside the network and must keep their value.
+	viper.SetDefault("user_suffix.port_vars", []string{"APP_PORT", "FORWARD_*", "VITE_HMR_PORT"})
+}
+
+// applyUserSuffix namespaces the compose project by the invoking user and
+// offsets the host port variables, so several developers can run the same
+// project on one machine. Named volumes are namespaced by compose itself
+// through the project name.
+func applyUserSuffix() error {
+
+	u, err := user.Current()
+	if err != nil {
+		return err
+	}
+
+	name := projectNameRe.ReplaceAllString(strings.ToLower(u.Username), "")
+	composeProject = projectName() + "_" + name
+
+	offset := viper.GetInt("user_suffix.port_offset")
+	if !viper.IsSet("user_suffix.port_offset") {
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return fmt.Errorf("can not derive a port offset for user %s, set user_suffix.port_offset", u.Username)
+		}
+		offset = uid % 100 * 100
+	}
+
+	patterns := viper.GetStringSlice("user_suffix.port_vars")
+	for _, kv := range os.Environ() {
+		key := strings.SplitN(kv, "=", 2)[0]
+		if !matchesAny(key, patterns) {
+			continue
+		}
+
+		port, err := strconv.Atoi(os.Getenv(key))
+		if err != nil {
+			continue
+		}
+		if port+offset > 65535 {
+			return fmt.Errorf("%s=%d is out of range with a port offset of %d", key, port, offset)
+		}
+
+		if err := setEnv(key, strconv.Itoa(port+offset)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchesAny reports whether name matches one of the glob patterns.
+func matchesAny(name string, patterns []string) bool {
+
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
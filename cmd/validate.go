@@ -0,0 +1,218 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewValidateCmd returns the validate command.
+func NewValidateCmd() *cobra.Command {
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the resolved environment against " + schemaFile,
+		Long: `Validate resolves the environment like run does and checks it against
+` + schemaFile + `, which lists the keys the project needs:
+
+  keys:
+    APP_ENV:
+      type: enum
+      enum: [local, staging, production]
+      default: local
+    APP_PORT:
+      type: port
+      required: true
+
+Types are string, int, bool, url, port, enum and json. Keys that are
+unset or empty get their default. Computed keys are derived from other
+keys, overriding any value in the dotenv file, so they can not drift
+apart:
+
+    DATABASE_URL:
+      computed: "mysql://${DB_USERNAME}:${DB_PASSWORD}@${DB_HOST}/${DB_DATABASE}"
+
+Each violation is followed by the key's description, from a "# @desc"
+comment above it in the dotenv file or the description in the schema. up
+validates too with --validate or when schema.validate is set in the
+config.
+
+With --strict keys annotated "# @deprecated use NEW_KEY" in the dotenv
+file or its layers fail the validation too, instead of only warning. The
+schema file is optional then.
+
+A summary with the number of warnings and suggestions follows the report
+unless the summary config is false.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := validate()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			printSummary(nil, nil)
+			if err != nil {
+				exit(1)
+			}
+		},
+	}
+
+	validateCmd.Flags().BoolVar(&flags.validateStrict, "strict", false, "fail on deprecated keys")
+
+	return validateCmd
+}
+
+// validate resolves the environment and checks it against the schema,
+// and with --strict for deprecated keys.
+func validate() error {
+
+	s, err := readSchema()
+	if err != nil {
+		return err
+	}
+	if s == nil && !flags.validateStrict {
+		return fmt.Errorf("%s", tr(message{ID: "ValidateNoSchema", Other: "can not find {{.File}} in the local directory"},
+			map[string]interface{}{"File": schemaFile}))
+	}
+
+	if err := loadEnvironment(); err != nil {
+		return err
+	}
+
+	var schemaErr error
+	if s != nil {
+		schemaErr = checkSchema(os.Stdout, s)
+	}
+
+	if flags.validateStrict {
+		if err := checkDeprecated(os.Stdout); err != nil && schemaErr == nil {
+			return err
+		}
+	}
+
+	return schemaErr
+}
+
+// checkDeprecated prints the deprecated keys of the dotenv file and its
+// layers to w and returns an error when there are any.
+func checkDeprecated(w io.Writer) error {
+
+	var deprecated []string
+	for _, layer := range dotenvLayers(dotenvFileName()) {
+		vars, err := parseEnvFile(layer)
+		if err != nil {
+			return err
+		}
+
+		d, err := deprecations(layer, vars)
+		if err != nil {
+			return err
+		}
+		deprecated = append(deprecated, d...)
+	}
+
+	for _, d := range deprecated {
+		fmt.Fprintln(w, d)
+	}
+
+	if len(deprecated) > 0 {
+		return fmt.Errorf("%s", tr(message{
+			ID:    "ValidateDeprecated",
+			One:   "the environment has {{.Count}} deprecated key",
+			Other: "the environment has {{.Count}} deprecated keys",
+		}, map[string]interface{}{"Count": len(deprecated)}))
+	}
+
+	return nil
+}
+
+// checkSchema applies the defaults of s, prints the violations to w and
+// returns an error when there are any.
+func checkSchema(w io.Writer, s *schema) error {
+
+	if err := s.applyDefaults(); err != nil {
+		return err
+	}
+
+	descs, err := keyDescriptions(s)
+	if err != nil {
+		return err
+	}
+
+	violations := s.violations(descs)
+	for _, v := range violations {
+		fmt.Fprintln(w, v)
+	}
+
+	if len(violations) > 0 {
+		err := &violationsError{msg: tr(message{
+			ID:    "ValidateViolations",
+			One:   "the environment has {{.Count}} schema violation",
+			Other: "the environment has {{.Count}} schema violations",
+		}, map[string]interface{}{"Count": len(violations)})}
+		for _, v := range violations {
+			err.violations = append(err.violations, v)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// violationsError is the error of checkSchema. It only counts the
+// violations, which were printed, and unwraps to their
+// *dotenv.ValidationError.
+type violationsError struct {
+	msg        string
+	violations []error
+}
+
+func (e *violationsError) Error() string {
+	return e.msg
+}
+
+func (e *violationsError) Unwrap() []error {
+	return e.violations
+}
+
+// setupSchema applies the schema defaults when up is run, and validates
+// the environment with --validate or the schema.validate config.
+func setupSchema() error {
+
+	s, err := readSchema()
+	if err != nil || s == nil {
+		return err
+	}
+
+	if !flags.validateOnUp && !viper.GetBool("schema.validate") {
+		return s.applyDefaults()
+	}
+
+	return checkSchema(os.Stderr, s)
+}
+
+// applySchema applies the schema defaults and computed keys, without
+// validating, for the commands that pass the environment on.
+func applySchema() error {
+
+	s, err := readSchema()
+	if err != nil || s == nil {
+		return err
+	}
+
+	return s.applyDefaults()
+}
@@ -0,0 +1,54 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+)
+
+func TestCheckSchemaValidationErrors(t *testing.T) {
+
+	testProject(t)
+	t.Setenv("LOADENV_TEST_PORT", "http")
+
+	schema := "keys:\n  LOADENV_TEST_PORT:\n    type: port\n  LOADENV_TEST_UNSET:\n    required: true\n"
+	if err := os.WriteFile(schemaFile, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := readSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkSchema(io.Discard, s)
+	var validationErr *dotenv.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("checkSchema = %#v, want a *dotenv.ValidationError", err)
+	}
+
+	keys := make(map[string]bool)
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		if errors.As(e, &validationErr) {
+			keys[validationErr.Key] = true
+		}
+	}
+	if len(keys) != 2 || !keys["LOADENV_TEST_PORT"] || !keys["LOADENV_TEST_UNSET"] {
+		t.Errorf("checkSchema violations are for %v, want LOADENV_TEST_PORT and LOADENV_TEST_UNSET", keys)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const gib = 1 << 30
+
+// vmResources are the CPUs and memory of the VM docker runs in.
+type vmResources struct {
+	runtime string
+	profile string
+	cpus    float64
+	memory  int64
+}
+
+// checkVMResources returns a warning when the Docker Desktop or Colima VM
+// is smaller than what the stack declares, the usual reason a local stack
+// is slow for no visible reason. The stack needs the sum of the services'
+// limits, or reservations, and at least resources.cpus and
+// resources.memory from the config.
+func checkVMResources() (string, error) {
+
+	vm, err := dockerVM()
+	if err != nil || vm == nil {
+		return "", err
+	}
+
+	cpus, memory, err := stackResources()
+	if err != nil {
+		return "", err
+	}
+
+	// the daemon reports the memory left after the VM's kernel, a little
+	// less than was allocated
+	if vm.cpus >= cpus && float64(vm.memory) >= 0.9*float64(memory) {
+		return "", nil
+	}
+
+	// resize to whole CPUs and GiB, never below what the VM has
+	wantCPUs := int(math.Max(math.Ceil(cpus), vm.cpus))
+	wantGiB := int(math.Ceil(math.Max(float64(memory), float64(vm.memory)) / gib))
+
+	data := map[string]interface{}{"CPUs": wantCPUs, "GiB": wantGiB}
+	howto := tr(message{
+		ID:    "DoctorVMResizeDesktop",
+		Other: "give it at least {{.CPUs}} CPUs and {{.GiB}} GB under Settings > Resources in Docker Desktop, then Apply & restart",
+	}, data)
+	if vm.runtime == "Colima" {
+		data["Profile"] = ""
+		if vm.profile != "default" {
+			data["Profile"] = " --profile " + vm.profile
+		}
+		howto = tr(message{
+			ID:    "DoctorVMResizeColima",
+			Other: "resize it with colima stop{{.Profile}} && colima start{{.Profile}} --cpu {{.CPUs}} --memory {{.GiB}}",
+		}, data)
+	}
+
+	return tr(message{
+		ID:    "DoctorVMResources",
+		Other: "{{.Runtime}} has {{.CPUs}} CPUs and {{.Memory}}GiB of memory but the stack declares {{.StackCPUs}} CPUs and {{.StackMemory}}GiB; {{.Howto}}",
+	}, map[string]interface{}{
+		"Runtime":     vm.runtime,
+		"CPUs":        strconv.FormatFloat(vm.cpus, 'g', -1, 64),
+		"Memory":      fmt.Sprintf("%.1f", float64(vm.memory)/gib),
+		"StackCPUs":   strconv.FormatFloat(cpus, 'g', -1, 64),
+		"StackMemory": fmt.Sprintf("%.1f", float64(memory)/gib),
+		"Howto":       howto,
+	}), nil
+}
+
+// dockerVM returns the resources of the Docker Desktop or Colima VM, or nil
+// when docker runs natively. They are asked from the daemon, and read from
+// the VM's settings when it is not running.
+func dockerVM() (*vmResources, error) {
+
+	lines, err := dockerLines("info", "--format", "{{.OperatingSystem}}\t{{.Name}}\t{{.NCPU}}\t{{.MemTotal}}")
+	if err != nil || len(lines) == 0 {
+		return vmSettings()
+	}
+
+	parts := strings.Split(lines[0], "\t")
+	if len(parts) < 4 {
+		return nil, nil
+	}
+
+	vm := &vmResources{}
+	switch {
+	case strings.Contains(parts[0], "Docker Desktop"):
+		vm.runtime = "Docker Desktop"
+	case parts[1] == "colima" || strings.HasPrefix(parts[1], "colima-"):
+		vm.runtime, vm.profile = "Colima", "default"
+		if parts[1] != "colima" {
+			vm.profile = strings.TrimPrefix(parts[1], "colima-")
+		}
+	default:
+		return nil, nil
+	}
+
+	vm.cpus, _ = strconv.ParseFloat(parts[2], 64)
+	vm.memory, _ = strconv.ParseInt(parts[3], 10, 64)
+
+	return vm, nil
+}
+
+// vmSettings reads the resources configured for the default Colima
+// profile, or else for Docker Desktop.
+func vmSettings() (*vmResources, error) {
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	colimaHome := os.Getenv("COLIMA_HOME")
+	if colimaHome == "" {
+		colimaHome = filepath.Join(home, ".colima")
+	}
+	if b, err := os.ReadFile(filepath.Join(colimaHome, "default", "colima.yaml")); err == nil {
+		var c struct {
+			CPU    float64 `yaml:"cpu"`
+			Memory float64 `yaml:"memory"`
+		}
+		if err := yaml.Unmarshal(b, &c); err != nil {
+			return nil, fmt.Errorf("can not parse the Colima config: %v", err)
+		}
+		return &vmResources{runtime: "Colima", profile: "default", cpus: c.CPU, memory: int64(c.Memory * gib)}, nil
+	}
+
+	// newer versions use settings-store.json, older ones settings.json,
+	// with differently cased keys
+	for _, fname := range []string{
+		filepath.Join(home, "Library", "Group Containers", "group.com.docker", "settings-store.json"),
+		filepath.Join(home, "Library", "Group Containers", "group.com.docker", "settings.json"),
+		filepath.Join(os.Getenv("APPDATA"), "Docker", "settings.json"),
+	} {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			continue
+		}
+		var settings map[string]interface{}
+		if err := json.Unmarshal(b, &settings); err != nil {
+			return nil, fmt.Errorf("can not parse %s: %v", fname, err)
+		}
+
+		vm := &vmResources{runtime: "Docker Desktop"}
+		for key, value := range settings {
+			n, ok := value.(float64)
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(key) {
+			case "cpus":
+				vm.cpus = n
+			case "memorymib":
+				vm.memory = int64(n) << 20
+			}
+		}
+		if vm.cpus == 0 || vm.memory == 0 {
+			continue
+		}
+		return vm, nil
+	}
+
+	return nil, nil
+}
+
+// stackResources returns the CPUs and memory the stack declares. Later
+// compose files override the values of earlier ones per service.
+func stackResources() (float64, int64, error) {
+
+	cpus := make(map[string]float64)
+	memory := make(map[string]int64)
+
+	for _, fname := range composeFiles() {
+		c, err := readComposeFile(fname)
+		if err != nil {
+			return 0, 0, err
+		}
+		for name, s := range c.Services {
+			limits, reservations := s.Deploy.Resources.Limits, s.Deploy.Resources.Reservations
+
+			for _, v := range []interface{}{s.CPUs, limits.CPUs, reservations.CPUs} {
+				if v == nil {
+					continue
+				}
+				n, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+				if err != nil {
+					return 0, 0, fmt.Errorf("%s: cpus of %s: %q is not a number", fname, name, v)
+				}
+				cpus[name] = n
+				break
+			}
+
+			for _, v := range []interface{}{s.MemLimit, limits.Memory, s.MemReservation, reservations.Memory} {
+				if v == nil {
+					continue
+				}
+				n, err := parseMemory(fmt.Sprint(v))
+				if err != nil {
+					return 0, 0, fmt.Errorf("%s: memory of %s: %v", fname, name, err)
+				}
+				memory[name] = n
+				break
+			}
+		}
+	}
+
+	var totalCPUs float64
+	for _, n := range cpus {
+		totalCPUs += n
+	}
+	var totalMemory int64
+	for _, n := range memory {
+		totalMemory += n
+	}
+
+	if floor := viper.GetFloat64("resources.cpus"); floor > totalCPUs {
+		totalCPUs = floor
+	}
+	if viper.IsSet("resources.memory") {
+		floor, err := parseMemory(viper.GetString("resources.memory"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("resources.memory: %v", err)
+		}
+		if floor > totalMemory {
+			totalMemory = floor
+		}
+	}
+
+	return totalCPUs, totalMemory, nil
+}
+
+// parseMemory parses a compose memory size, e.g. 512m, 2g or 1gb, or a
+// number of bytes.
+func parseMemory(s string) (int64, error) {
+
+	size := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "b")
+
+	unit := int64(1)
+	if n := len(size); n > 0 {
+		if i := strings.IndexByte("kmgt", size[n-1]); i >= 0 {
+			unit = int64(1) << (10 * uint(i+1))
+			size = size[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(size, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a memory size", s)
+	}
+
+	return int64(n * float64(unit)), nil
+}
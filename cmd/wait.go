@@ -0,0 +1,184 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shaybix/loadenv/pkg/loadenv"
+	"github.com/spf13/viper"
+)
+
+// waitInterval is the delay between two checks of the stack.
+const waitInterval = time.Second
+
+func init() {
+	viper.SetDefault("up.wait_timeout", "2m")
+	viper.SetDefault("up.wait_probe_timeout", "2s")
+}
+
+// waitStack waits until every container of the stack is running and
+// healthy if it has a health check, or else listens on the TCP ports it
+// publishes. One-shot containers that exited with 0 are done. It returns an error listing the services that are not ready
+// when they are still not after --wait-timeout or up.wait_timeout.
+func waitStack() error {
+
+	timeout := flags.upWaitTimeout
+	if timeout == 0 {
+		timeout = viper.GetDuration("up.wait_timeout")
+	}
+	deadline := time.Now().Add(timeout)
+
+	info("Waiting up to %s for the services to be ready\n", timeout)
+
+	ready := make(map[string]bool)
+	for {
+		statuses, err := stackStatus()
+		if err != nil {
+			return err
+		}
+
+		notReady := make(map[string]string)
+		for _, s := range statuses {
+			if reason := serviceNotReady(s); reason != "" {
+				notReady[s.Service] = reason
+			}
+		}
+		for _, s := range statuses {
+			if _, ok := notReady[s.Service]; ok || ready[s.Service] {
+				continue
+			}
+			ready[s.Service] = true
+			if err := flags.events.Emit(loadenv.ServiceHealthy{Project: stackProject(), Service: s.Service}); err != nil {
+				return err
+			}
+		}
+		if len(statuses) > 0 && len(notReady) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if len(statuses) == 0 {
+				return fmt.Errorf("the stack has no containers after %s", timeout)
+			}
+			var names []string
+			for name := range notReady {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			lines := []string{fmt.Sprintf("%d service(s) not ready after %s:", len(names), timeout)}
+			for _, name := range names {
+				lines = append(lines, fmt.Sprintf("  %s: %s", name, notReady[name]))
+			}
+			return fmt.Errorf("%s", strings.Join(lines, "\n"))
+		}
+
+		time.Sleep(waitInterval)
+	}
+}
+
+// serviceNotReady returns why the container of s is not ready, or "" if
+// it is.
+func serviceNotReady(s serviceStatus) string {
+
+	switch {
+	case s.State == "exited" && s.exitCode == 0:
+		// one-shot containers, e.g. migrations, are done once they exit
+		return ""
+	case s.State == "exited":
+		return fmt.Sprintf("exited with code %d", s.exitCode)
+	case s.State != "running":
+		return s.State
+	}
+
+	// the health check knows best whether the service is up
+	if s.Health != "" {
+		if s.Health != "healthy" {
+			return s.Health
+		}
+		return ""
+	}
+
+	for _, port := range s.Ports {
+		mapping := strings.SplitN(port, "->", 2)
+		// ranges, e.g. 8000-8001->8000-8001/tcp, are not probed
+		if len(mapping) < 2 || !strings.HasSuffix(mapping[1], "/tcp") || strings.Contains(mapping[0], "-") {
+			continue
+		}
+		if !portListening(s.Container, mapping[0], strings.TrimSuffix(mapping[1], "/tcp")) {
+			return fmt.Sprintf("port %s does not accept connections", mapping[0])
+		}
+	}
+
+	return ""
+}
+
+// portListening reports whether the service in container listens on
+// containerPort, published on hostPort. docker-proxy accepts connections
+// on the host port before the service listens, so the port is looked up
+// in the container, and only probed on the host when the container has no
+// cat to do so.
+func portListening(container, hostPort, containerPort string) bool {
+
+	if listening, err := containerListens(container, containerPort); err == nil {
+		return listening
+	}
+
+	timeout := flags.upWaitProbeTimeout
+	if timeout == 0 {
+		timeout = viper.GetDuration("up.wait_probe_timeout")
+	}
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:"+hostPort, timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	return true
+}
+
+// containerListens reports whether a socket of container listens on the
+// TCP port, from the /proc/net/tcp tables of its network namespace.
+func containerListens(container, port string) (bool, error) {
+
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return false, err
+	}
+
+	// tcp6 is missing when IPv6 is disabled, which fails cat after
+	// printing tcp
+	out, err := dockerOutput(exec.Command("docker", "exec", container, "cat", "/proc/net/tcp", "/proc/net/tcp6"))
+	if err != nil && len(out) == 0 {
+		return false, err
+	}
+
+	// e.g. "0: 00000000:1F90 00000000:0000 0A ...", 0A is LISTEN
+	suffix := fmt.Sprintf(":%04X", n)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 3 && strings.HasSuffix(fields[1], suffix) && fields[3] == "0A" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
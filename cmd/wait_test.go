@@ -0,0 +1,38 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "testing"
+
+func TestServiceNotReady(t *testing.T) {
+
+	tests := []struct {
+		s    serviceStatus
+		want string
+	}{
+		{serviceStatus{State: "exited"}, ""},
+		{serviceStatus{State: "exited", exitCode: 2}, "exited with code 2"},
+		{serviceStatus{State: "created"}, "created"},
+		{serviceStatus{State: "running", Health: "starting"}, "starting"},
+		// the health check is trusted over the published ports
+		{serviceStatus{State: "running", Health: "healthy", Ports: []string{"1->80/tcp"}}, ""},
+		{serviceStatus{State: "running"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := serviceNotReady(tt.s); got != tt.want {
+			t.Errorf("serviceNotReady(%+v) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shaybix/loadenv/pkg/dotenv"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// projectConfigFile returns the name of the config file in the project
+// directory.
+func projectConfigFile() string {
+	return flags.configName + ".yaml"
+}
+
+// maybeRunWizard offers the setup wizard when the project has no config
+// file and loadenv is run from a terminal.
+func maybeRunWizard() error {
+
+	if flags.cfgFile != "" || flags.quiet || checkReadOnly("setup") != nil {
+		return nil
+	}
+	if _, err := os.Stat(projectConfigFile()); err == nil {
+		return nil
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	if !strings.HasPrefix(strings.ToLower(ask(in, "No "+projectConfigFile()+" found, set up loadenv for this project?", "Y")), "y") {
+		fmt.Fprintf(os.Stderr, "Skipping, create an empty %s to stop being asked.\n", projectConfigFile())
+		return nil
+	}
+
+	return runWizard(in)
+}
+
+// runWizard asks about the project and writes the project config file.
+func runWizard(in *bufio.Reader) error {
+
+	framework := "unknown framework"
+	if _, err := os.Stat("artisan"); err == nil {
+		framework = "Laravel"
+	}
+	frontend := detectFrontend()
+	if frontend != "" {
+		framework += " with " + frontend
+	}
+	fmt.Fprintf(os.Stderr, "Detected %s.\n\n", framework)
+
+	services := stackServices()
+	app := viper.GetString("app_service")
+	if len(services) > 0 {
+		fmt.Fprintf(os.Stderr, "Services in the compose file: %s\n", strings.Join(services, ", "))
+		if !hasString(services, app) {
+			app = services[0]
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# generated by the loadenv setup wizard\n\n")
+
+	app = ask(in, "Service running the PHP application", app)
+	writeConfigKey(&b, "service the node, --perf and schedule:work commands attach to", "app_service", app)
+
+	dialect := ask(in, "Dotenv syntax of "+dotenvFileName()+" (posix, docker, ruby, node or blank for loadenv's own)", "")
+	if dialect != "" {
+		if _, err := dotenv.LookupDialect(dialect); err != nil {
+			return err
+		}
+		writeConfigKey(&b, "syntax used to parse the dotenv file", "dialect", dialect)
+	}
+
+	if frontend != "" {
+		command := ask(in, "Command starting the "+frontend+" dev server for --node", "")
+		if command != "" {
+			writeConfigKey(&b, "command of the node service added by --node", "node.command", command)
+		}
+	}
+
+	ports := ask(in, "Variables holding host ports, offset by --user-suffix", strings.Join(viper.GetStringSlice("user_suffix.port_vars"), ","))
+	writeConfigKey(&b, "host port variables offset per user by --user-suffix", "user_suffix.port_vars", strings.Split(ports, ","))
+
+	if err := os.WriteFile(projectConfigFile(), b.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if err := mergeProjectConfig(projectConfigFile()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, `
+Wrote %s, commit it so the whole team shares the settings.
+
+When the stack runs loadenv also creates, in .loadenv/:
+  tmp/         compose override files generated for the enabled options
+  state.json   what was started, so it can be stopped later
+Both are removed when the stack is stopped, add .loadenv/ to .gitignore
+(loadenv doctor --fix does this).
+
+`, projectConfigFile())
+
+	return nil
+}
+
+// ask prints question and returns the trimmed answer, or def when the
+// answer is empty.
+func ask(in *bufio.Reader, question, def string) string {
+
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+
+	answer, _ := in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+
+	return answer
+}
+
+// writeConfigKey writes the dotted key with value and a comment to b.
+func writeConfigKey(b *bytes.Buffer, comment, key string, value interface{}) {
+
+	// nest dotted keys, viper reads them as sections
+	parts := strings.Split(key, ".")
+	var tree interface{} = value
+	for i := len(parts) - 1; i >= 0; i-- {
+		tree = map[string]interface{}{parts[i]: tree}
+	}
+
+	out, _ := yaml.Marshal(tree)
+	fmt.Fprintf(b, "# %s\n%s\n", comment, out)
+}
+
+// hasString reports whether list contains s.
+func hasString(list []string, s string) bool {
+
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
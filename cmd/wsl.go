@@ -0,0 +1,153 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("wsl.translate_paths", true)
+}
+
+// isWSL reports whether loadenv runs inside the Windows Subsystem for Linux.
+func isWSL() bool {
+
+	if runtime.GOOS != "linux" {
+		return false
+	}
+
+	b, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(string(b)), "microsoft")
+}
+
+var windowsMountRe = regexp.MustCompile(`^/mnt/[a-z](/|$)`)
+
+// checkWSLMount returns a warning when the project lives on a Windows
+// drive mounted into WSL, where bind mounts cross the slow 9p boundary.
+func checkWSLMount() (string, error) {
+
+	if !isWSL() {
+		return "", nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	if !windowsMountRe.MatchString(dir) {
+		return "", nil
+	}
+
+	return tr(message{
+		ID:    "DoctorWSLMount",
+		Other: "{{.Dir}} is on the Windows filesystem, bind mounts will be slow; move the project into the Linux filesystem (e.g. ~/{{.Name}})",
+	}, map[string]interface{}{"Dir": dir, "Name": filepath.Base(dir)}), nil
+}
+
+var windowsPathRe = regexp.MustCompile(`^([A-Za-z]):([\\/].*)$`)
+
+// wslPath returns the path of the Windows path p, e.g. C:\src, in WSL,
+// /mnt/c/src, and whether p is one.
+func wslPath(p string) (string, bool) {
+
+	m := windowsPathRe.FindStringSubmatch(p)
+	if m == nil {
+		return "", false
+	}
+
+	rest := strings.TrimRight(strings.ReplaceAll(m[2], `\`, "/"), "/")
+
+	return "/mnt/" + strings.ToLower(m[1]) + rest, true
+}
+
+// windowsPath returns the Windows path of the path p of a Windows drive
+// mounted in WSL, e.g. /mnt/c/src, C:\src, and whether p is one.
+func windowsPath(p string) (string, bool) {
+
+	if !windowsMountRe.MatchString(p) {
+		return "", false
+	}
+
+	return strings.ToUpper(p[5:6]) + `:\` + strings.ReplaceAll(strings.Trim(p[6:], "/"), "/", `\`), true
+}
+
+// translatePaths rewrites the loaded values that are paths of the other
+// side of WSL, so a dotenv file shared between WSL and Windows users
+// mounts the same directories: C:\src becomes /mnt/c/src in WSL, and
+// /mnt/c/src becomes C:\src on Windows.
+func translatePaths() error {
+
+	translate := windowsPath
+	if isWSL() {
+		translate = wslPath
+	} else if runtime.GOOS != "windows" {
+		return nil
+	}
+
+	for _, key := range loadedKeys {
+		value := os.Getenv(key)
+		path, ok := translate(value)
+		if !ok {
+			continue
+		}
+		if err := setEnv(key, path); err != nil {
+			return err
+		}
+		if flags.verbose {
+			info("%s translated from %s to %s\n", key, value, path)
+		}
+	}
+
+	return nil
+}
+
+// setupPlatform adjusts the environment for the platform loadenv runs on
+// and warns about known slow setups. Paths of the other side of WSL are
+// translated unless wsl.translate_paths is false.
+func setupPlatform() error {
+
+	// docker-compose on Windows only translates C:\ style volume paths
+	// when asked to.
+	if runtime.GOOS == "windows" {
+		setDefaultEnv("COMPOSE_CONVERT_WINDOWS_PATHS", "1")
+	}
+
+	if viper.GetBool("wsl.translate_paths") {
+		if err := translatePaths(); err != nil {
+			return err
+		}
+	}
+
+	warning, err := checkWSLMount()
+	if err != nil {
+		return err
+	}
+	if warning != "" {
+		warn("%s\n", warning)
+	}
+
+	return nil
+}
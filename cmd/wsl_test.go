@@ -0,0 +1,46 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "testing"
+
+func TestWSLPathTranslation(t *testing.T) {
+
+	for p, want := range map[string]string{
+		`C:\Users\me\src`: "/mnt/c/Users/me/src",
+		`d:/projects/`:    "/mnt/d/projects",
+		`C:\`:             "/mnt/c",
+	} {
+		if got, ok := wslPath(p); !ok || got != want {
+			t.Errorf("wslPath(%q) = %q, %v, want %q", p, got, ok, want)
+		}
+	}
+	for _, p := range []string{"/home/me", "http://localhost", "C:", "localhost:3306"} {
+		if got, ok := wslPath(p); ok {
+			t.Errorf("wslPath(%q) = %q, want no Windows path", p, got)
+		}
+	}
+
+	for p, want := range map[string]string{
+		"/mnt/c/Users/me/src": `C:\Users\me\src`,
+		"/mnt/d/":             `D:\`,
+	} {
+		if got, ok := windowsPath(p); !ok || got != want {
+			t.Errorf("windowsPath(%q) = %q, %v, want %q", p, got, ok, want)
+		}
+	}
+	if got, ok := windowsPath("/mnt/wsl/docker"); ok {
+		t.Errorf("windowsPath(/mnt/wsl/docker) = %q, want no Windows drive", got)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Dialect describes the .env syntax of a tool so files written for it
+// load identically under loadenv.
+type Dialect struct {
+	// export allows an "export " prefix before the key.
+	export bool
+	// quotes lists the quote characters stripped around values.
+	quotes string
+	// escapes are the sequences expanded inside double quotes.
+	escapes map[byte]string
+	// inlineComments cuts unquoted values at a " #".
+	inlineComments bool
+	// trimSpace trims whitespace around keys and unquoted values.
+	trimSpace bool
+	// interpolate marks dialects where ${VAR} and $VAR are expanded.
+	interpolate bool
+	// inheritBare makes a line with only a key take the host's value.
+	inheritBare bool
+	// multiline lets quoted values span lines, and joins lines ending in
+	// a backslash outside quotes with the next one.
+	multiline bool
+}
+
+// dialects are the dialects known by name.
+var dialects = map[string]Dialect{
+	"posix": {
+		export:         true,
+		quotes:         `'"`,
+		escapes:        map[byte]string{'"': `"`, '\\': `\`, '$': `$`, '`': "`"},
+		inlineComments: true,
+		trimSpace:      true,
+		interpolate:    true,
+		multiline:      true,
+	},
+	"docker": {
+		// docker --env-file takes everything after the = verbatim
+		inheritBare: true,
+	},
+	"ruby": {
+		export:         true,
+		quotes:         `'"`,
+		escapes:        map[byte]string{'n': "\n", 't': "\t", '"': `"`, '\\': `\`, '$': `$`},
+		inlineComments: true,
+		trimSpace:      true,
+		interpolate:    true,
+		multiline:      true,
+	},
+	"node": {
+		export:         true,
+		quotes:         "'\"`",
+		escapes:        map[byte]string{'n': "\n"},
+		inlineComments: true,
+		trimSpace:      true,
+		multiline:      true,
+	},
+}
+
+// defaultDialect is loadenv's own syntax, used when no dialect is chosen.
+// Values may contain =, and may be quoted to keep spaces or a " #". An
+// "export " prefix is allowed so files can be sourced by the shell too.
+var defaultDialect = Dialect{
+	export:         true,
+	quotes:         `'"`,
+	escapes:        map[byte]string{'n': "\n", 't': "\t", '"': `"`, '\\': `\`, '$': `$`},
+	inlineComments: true,
+	trimSpace:      true,
+	interpolate:    true,
+	multiline:      true,
+}
+
+// LookupDialect returns the dialect called name, one of posix, docker,
+// ruby or node.
+func LookupDialect(name string) (*Dialect, error) {
+
+	d, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %q, use posix, docker, ruby or node", name)
+	}
+
+	return &d, nil
+}
+
+// Interpolates reports whether the dialect expands ${VAR} and $VAR.
+func (d *Dialect) Interpolates() bool {
+	return d.interpolate
+}
+
+// parseLine parses a single line of a dotenv file. ok is false for lines
+// that do not define a variable.
+func (d *Dialect) parseLine(line string) (v Var, ok bool, err error) {
+	return d.parseLineExpand(line, nil)
+}
+
+// parseLineExpand is parseLine expanding variable references in unquoted
+// and double quoted values with lookup, unless it is nil.
+func (d *Dialect) parseLineExpand(line string, lookup lookupFunc) (v Var, ok bool, err error) {
+
+	def, ok, err := d.parseDefinition(line, lookup)
+
+	return def.Var, ok, err
+}
+
+// definition is a variable defined by a line.
+type definition struct {
+	Var
+	// keyAt is the offset of the key in the line, after any indentation
+	// and export prefix.
+	keyAt int
+	// comment is the inline comment after the value, if any.
+	comment string
+}
+
+// parseDefinition is parseLineExpand also returning where the key starts
+// and the comment after the value.
+func (d *Dialect) parseDefinition(line string, lookup lookupFunc) (def definition, ok bool, err error) {
+
+	rest := strings.TrimLeft(line, " \t")
+	if rest == "" || strings.HasPrefix(rest, "#") {
+		return def, false, nil
+	}
+
+	if d.export && len(rest) > len("export") && strings.HasPrefix(rest, "export") && (rest[6] == ' ' || rest[6] == '\t') {
+		rest = strings.TrimLeft(rest[len("export"):], " \t")
+	}
+	def.keyAt = len(line) - len(rest)
+
+	i := strings.Index(rest, "=")
+	if i < 0 {
+		key := strings.TrimSpace(rest)
+		if d.inheritBare && isName(key) {
+			value, set := os.LookupEnv(key)
+			def.Var = Var{Key: key, Value: value}
+			return def, set, nil
+		}
+		return def, false, fmt.Errorf("invalid line %q", rest)
+	}
+
+	key, value := rest[:i], rest[i+1:]
+	if d.trimSpace {
+		key = strings.TrimSpace(key)
+		value = strings.TrimLeft(value, " \t")
+	}
+	// keys end up in shell code, so only names every shell takes are read
+	if !isName(key) {
+		return def, false, fmt.Errorf("invalid line %q", rest)
+	}
+	def.Key = key
+
+	if value != "" && strings.IndexByte(d.quotes, value[0]) >= 0 {
+		var n int
+		def.Value, n, err = d.unquote(value, lookup)
+		if err != nil {
+			return def, false, err
+		}
+
+		after := value[n:]
+		if t := strings.TrimLeft(after, " \t"); d.inlineComments && strings.HasPrefix(t, "#") {
+			// the comment has to follow a space to stay one after an
+			// unquoted value
+			ws := after[:len(after)-len(t)]
+			if !strings.HasSuffix(ws, " ") {
+				ws += " "
+			}
+			def.comment = ws + t
+		}
+		return def, true, nil
+	}
+
+	if d.inlineComments {
+		if j := strings.Index(value, " #"); j >= 0 {
+			// the comment keeps the whitespace aligning it
+			k := len(strings.TrimRight(value[:j], " \t"))
+			def.comment = value[k:]
+			value = value[:k]
+		}
+	}
+	if d.trimSpace {
+		value = strings.TrimSpace(value)
+	}
+
+	if lookup != nil {
+		if value, err = expandValue(value, lookup); err != nil {
+			return def, false, err
+		}
+	}
+	def.Value = value
+
+	return def, true, nil
+}
+
+// continues reports whether line is continued on the next line, because
+// a quoted value is not closed yet or the line ends in a backslash, and
+// returns the line without the backslash.
+func (d *Dialect) continues(line string) (string, bool) {
+
+	if !d.multiline || strings.HasPrefix(strings.TrimLeft(line, " \t"), "#") {
+		return line, false
+	}
+
+	i := strings.Index(line, "=")
+	if i < 0 {
+		return line, false
+	}
+	value := strings.TrimLeft(line[i+1:], " \t")
+
+	if value != "" && strings.IndexByte(d.quotes, value[0]) >= 0 {
+		q := value[0]
+		for j := 1; j < len(value); j++ {
+			if q == '"' && value[j] == '\\' {
+				j++
+				continue
+			}
+			if value[j] == q {
+				return line, false
+			}
+		}
+		return line, true
+	}
+
+	// an even number of backslashes are escaped ones kept in the value
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	if n%2 == 1 {
+		return line[:len(line)-1], true
+	}
+
+	return line, false
+}
+
+// definitionRe matches the start of a line defining a variable.
+var definitionRe = regexp.MustCompile(`^[ \t]*(export[ \t]+)?[A-Za-z_][A-Za-z0-9_]*[ \t]*=`)
+
+// unquote strips the quotes around s, expanding the dialect's escapes in
+// double quoted values, and variable references too when lookup is not
+// nil. It returns the offset just past the closing quote as well; what
+// follows is ignored.
+func (d *Dialect) unquote(s string, lookup lookupFunc) (string, int, error) {
+
+	q := s[0]
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+
+		if c == q {
+			return b.String(), i + 1, nil
+		}
+
+		if q == '"' && c == '\\' && i+1 < len(s) {
+			if repl, ok := d.escapes[s[i+1]]; ok {
+				b.WriteString(repl)
+				i++
+				continue
+			}
+		}
+
+		if q == '"' && c == '$' && lookup != nil {
+			value, n, err := expandRef(s[i:], lookup)
+			if err != nil {
+				return "", 0, err
+			}
+			b.WriteString(value)
+			i += n - 1
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted value %s", s)
+}
+
+// varRefRe matches ${VAR} and $VAR references.
+var varRefRe = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*[^}]*\}|\$[A-Za-z_][A-Za-z0-9_]*`)
+
+// bareValueRe matches values every dialect reads back unchanged without quotes.
+var bareValueRe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// Format returns the line defining v in the dialect's syntax, or loadenv's
+// own syntax if d is nil. When interpolate is false any $ in the value is
+// written so it stays literal. It fails if the value can not be
+// represented exactly.
+func (d *Dialect) Format(v Var, interpolate bool) (string, error) {
+
+	if d == nil {
+		d = &defaultDialect
+	}
+
+	value := v.Value
+	hasRefs := varRefRe.MatchString(strings.Replace(value, "$$", "", -1))
+
+	if hasRefs && interpolate && !d.interpolate {
+		return "", fmt.Errorf("%s references other variables, which the target dialect does not expand", v.Key)
+	}
+
+	var line string
+	switch {
+	case d.quotes == "" || (bareValueRe.MatchString(value) && !strings.Contains(value, "$")):
+		line = v.Key + "=" + value
+	case !(interpolate && hasRefs) && strings.ContainsRune(d.quotes, '\'') && !strings.Contains(value, "'") && (d.multiline || !strings.Contains(value, "\n")):
+		// single quotes are literal in every dialect
+		line = v.Key + "='" + value + "'"
+	default:
+		quoted, ok := d.doubleQuote(value, interpolate)
+		if !ok {
+			return "", fmt.Errorf("%s can not be represented in the target dialect", v.Key)
+		}
+		line = v.Key + "=" + quoted
+	}
+
+	// make sure the value reads back exactly as it was
+	parsed, ok, err := d.parseLine(line)
+	if err != nil || !ok || parsed.Value != value || (strings.Contains(line, "\n") && !d.multiline) {
+		return "", fmt.Errorf("%s can not be represented in the target dialect", v.Key)
+	}
+
+	return line, nil
+}
+
+// doubleQuote quotes s with double quotes, escaping what the dialect can
+// escape. ok is false when s contains characters that can not be written.
+func (d *Dialect) doubleQuote(s string, interpolate bool) (quoted string, ok bool) {
+
+	if !strings.ContainsRune(d.quotes, '"') {
+		return "", false
+	}
+
+	// invert the escape table to map characters to their escape letter
+	escapes := make(map[byte]byte)
+	for c, repl := range d.escapes {
+		if len(repl) == 1 {
+			escapes[repl[0]] = c
+		}
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if c == '$' && interpolate {
+			b.WriteByte(c)
+			continue
+		}
+
+		if e, ok := escapes[c]; ok {
+			b.WriteByte('\\')
+			b.WriteByte(e)
+			continue
+		}
+
+		if c == '"' || (c == '\n' && !d.multiline) || (c == '$' && d.interpolate) {
+			return "", false
+		}
+
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+
+	return b.String(), true
+}
@@ -0,0 +1,181 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"strings"
+	"testing"
+)
+
+// testDialects are the dialects by name, loadenv's own syntax as "".
+func testDialects(t *testing.T) map[string]*Dialect {
+
+	dialects := map[string]*Dialect{"": nil}
+	for _, name := range []string{"posix", "docker", "ruby", "node"} {
+		d, err := LookupDialect(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dialects[name] = d
+	}
+
+	return dialects
+}
+
+func TestLookupDialect(t *testing.T) {
+
+	if _, err := LookupDialect("cobol"); err == nil {
+		t.Error("LookupDialect of an unknown dialect succeeded, want an error")
+	}
+}
+
+func TestDialects(t *testing.T) {
+
+	tests := []struct {
+		dialect string
+		in      string
+		want    string
+	}{
+		{"posix", "export A='x y'\n", "x y"},
+		{"posix", `A="a\nb"` + "\n", `a\nb`},
+		{"posix", `A="a\$b"` + "\n", "a$b"},
+		{"ruby", `A="a\nb"` + "\n", "a\nb"},
+		{"node", "A=`x y`\n", "x y"},
+		{"node", `A="a\nb"` + "\n", "a\nb"},
+		{"docker", "A='x' # not a comment\n", "'x' # not a comment"},
+		{"docker", "A= padded \n", " padded "},
+		{"docker", "A=x\\\n", `x\`},
+	}
+
+	dialects := testDialects(t)
+	for _, tt := range tests {
+		vars, err := Read(strings.NewReader(tt.in), tt.dialect, dialects[tt.dialect], true)
+		if err != nil {
+			t.Errorf("%s: Read(%q): %v", tt.dialect, tt.in, err)
+			continue
+		}
+		if len(vars) != 1 || vars[0].Value != tt.want {
+			t.Errorf("%s: Read(%q) = %v, want A=%q", tt.dialect, tt.in, vars, tt.want)
+		}
+	}
+
+	// docker --env-file passes a bare key through from the host
+	t.Setenv("LOADENV_TEST_HOST", "from-host")
+	vars, err := Read(strings.NewReader("LOADENV_TEST_HOST\nLOADENV_TEST_UNSET\n"), "docker", dialects["docker"], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 1 || vars[0].Value != "from-host" {
+		t.Errorf("docker: bare keys = %v, want only LOADENV_TEST_HOST=from-host", vars)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+
+	values := []string{
+		"",
+		"plain",
+		"mysql://u:p@host/db?x=y",
+		"with space",
+		" padded ",
+		"a#b",
+		"x # y",
+		"it's",
+		`say "hi"`,
+		`both ' and "`,
+		"line1\nline2",
+		"-----BEGIN KEY-----\nabc\n-----END KEY-----\n",
+		"$literal",
+		"${literal}",
+		`back\slash`,
+		`C:\dir\`,
+		"tab\there",
+		"`backtick`",
+	}
+
+	for name, d := range testDialects(t) {
+		for _, value := range values {
+			v := Var{Key: "KEY", Value: value}
+
+			line, err := d.Format(v, false)
+			if err != nil {
+				// not every value can be written in every dialect,
+				// but those without special characters can
+				if value == "plain" || value == "" || value == "mysql://u:p@host/db?x=y" {
+					t.Errorf("%q: Format(%q): %v", name, value, err)
+				}
+				continue
+			}
+
+			// a line after the value must stay a line of its own
+			in := line + "\nNEXT=1\n"
+			vars, err := Read(strings.NewReader(in), "test", d, true)
+			if err != nil {
+				t.Errorf("%q: Format(%q) = %q, which does not read back: %v", name, value, line, err)
+				continue
+			}
+			if len(vars) != 2 || vars[0] != v || vars[1] != (Var{"NEXT", "1"}) {
+				t.Errorf("%q: Format(%q) = %q, which reads back as %q", name, value, line, vars)
+			}
+		}
+	}
+}
+
+func TestFormatNotRepresentable(t *testing.T) {
+
+	dialects := testDialects(t)
+
+	tests := []struct {
+		dialect string
+		value   string
+	}{
+		// docker keeps the value verbatim up to the end of the line
+		{"docker", "line1\nline2"},
+	}
+
+	for _, tt := range tests {
+		line, err := dialects[tt.dialect].Format(Var{Key: "KEY", Value: tt.value}, false)
+		if err == nil {
+			t.Errorf("%s: Format(%q) = %q, want an error", tt.dialect, tt.value, line)
+		}
+	}
+}
+
+func TestFormatInterpolate(t *testing.T) {
+
+	for name, d := range testDialects(t) {
+		v := Var{Key: "B", Value: "${A}/x"}
+
+		line, err := d.Format(v, true)
+		if d != nil && !d.Interpolates() {
+			if err == nil {
+				t.Errorf("%q: Format(%q, true) = %q, want an error as it does not expand references", name, v.Value, line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: Format(%q, true): %v", name, v.Value, err)
+			continue
+		}
+
+		vars, err := Read(strings.NewReader("A=1\n"+line+"\n"), "test", d, true)
+		if err != nil {
+			t.Errorf("%q: Read(%q): %v", name, line, err)
+			continue
+		}
+		if vars[1].Value != "1/x" {
+			t.Errorf("%q: Format(%q, true) = %q, which expands to %q", name, v.Value, line, vars[1].Value)
+		}
+	}
+}
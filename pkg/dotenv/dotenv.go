@@ -0,0 +1,219 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dotenv parses dotenv files the way loadenv does, so other Go
+// programs can load them without shelling out to the binary.
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Var is a single variable read from a dotenv file.
+type Var struct {
+	Key   string
+	Value string
+}
+
+// Parse reads dotenv formatted variables from r with loadenv's own syntax,
+// expanding references, and returns them by key.
+func Parse(r io.Reader) (map[string]string, error) {
+
+	vars, err := Read(r, "dotenv", nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, v := range vars {
+		env[v.Key] = v.Value
+	}
+
+	return env, nil
+}
+
+// Load sets the variables of the given files, .env when none are given,
+// in the process environment. Variables that are already set keep their
+// value. Errors in a file are a *ParseError.
+func Load(filenames ...string) error {
+	return load(false, filenames)
+}
+
+// Overload is Load, but replaces the values of variables already set.
+func Overload(filenames ...string) error {
+	return load(true, filenames)
+}
+
+func load(overload bool, filenames []string) error {
+
+	if len(filenames) == 0 {
+		filenames = []string{".env"}
+	}
+
+	for _, fname := range filenames {
+		f, err := os.Open(fname)
+		if err != nil {
+			return err
+		}
+
+		vars, err := Read(f, fname, nil, true)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, v := range vars {
+			if _, set := os.LookupEnv(v.Key); set && !overload {
+				continue
+			}
+			if err := os.Setenv(v.Key, v.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Read reads the variables from r in file order with dialect d, or
+// loadenv's own syntax if d is nil. name is used in error messages, which
+// are a *ParseError for errors in a definition. When expand is set and
+// the dialect interpolates, references are resolved from the earlier
+// variables and then the process environment.
+func Read(r io.Reader, name string, d *Dialect, expand bool) ([]Var, error) {
+
+	if d == nil {
+		d = &defaultDialect
+	}
+
+	var vars []Var
+
+	var lookup lookupFunc
+	if expand && d.interpolate {
+		lookup = func(key string) (string, bool) {
+			for i := len(vars) - 1; i >= 0; i-- {
+				if vars[i].Key == key {
+					return vars[i].Value, true
+				}
+			}
+			return os.LookupEnv(key)
+		}
+	}
+
+	err := d.scan(r, name, func(start int, line, text string) error {
+		v, ok, err := d.parseLineExpand(line, lookup)
+		if err != nil {
+			return &ParseError{Name: name, Line: start, Err: err}
+		}
+		if ok {
+			vars = append(vars, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// Line is a logical line of a dotenv file: a variable definition, which
+// may span several physical lines, a comment or a blank line.
+type Line struct {
+	// Text is the line as written, its physical lines joined by newlines.
+	Text string
+	// Var is the variable the line defines, nil for comments and blank
+	// lines.
+	Var *Var
+	// KeyOffset is the offset of the key in Text, after any indentation
+	// and export prefix, which Text[:KeyOffset] keeps.
+	KeyOffset int
+	// Comment is the " # ..." after the value, quoted or not, if any.
+	Comment string
+}
+
+// ReadLines reads the logical lines of r in file order with dialect d, or
+// loadenv's own syntax if d is nil, without expanding references, so a
+// file can be rewritten with its comments kept. name is used in error
+// messages.
+func ReadLines(r io.Reader, name string, d *Dialect) ([]Line, error) {
+
+	if d == nil {
+		d = &defaultDialect
+	}
+
+	var lines []Line
+
+	err := d.scan(r, name, func(start int, line, text string) error {
+		def, ok, err := d.parseDefinition(line, nil)
+		if err != nil {
+			return &ParseError{Name: name, Line: start, Err: err}
+		}
+		l := Line{Text: text}
+		if ok {
+			l.Var = &def.Var
+			l.KeyOffset, l.Comment = def.keyAt, def.comment
+		}
+		lines = append(lines, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// scan calls fn with every logical line of r, the lines of multi-line
+// values joined, along with the number of its first line and the text as
+// written. name is used in error messages.
+func (d *Dialect) scan(r io.Reader, name string, fn func(start int, line, text string) error) error {
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		start := n
+		line := scanner.Text()
+		text := line
+
+		// join the lines of a multi-line value
+		for {
+			joined, more := d.continues(line)
+			if !more || !scanner.Scan() {
+				line = joined
+				break
+			}
+			n++
+			text += "\n" + scanner.Text()
+			if joined != line {
+				// a backslash continuation, like in the shell, unless it
+				// would swallow the next definition, as a value ending
+				// in a backslash such as a Windows path does
+				if definitionRe.MatchString(scanner.Text()) {
+					return &ParseError{Name: name, Line: start, Err: fmt.Errorf("the value ends in a backslash, which continues it on line %d defining another variable; quote the value to keep the backslash", n)}
+				}
+				line = joined + scanner.Text()
+			} else {
+				line += "\n" + scanner.Text()
+			}
+		}
+
+		if err := fn(start, line, text); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
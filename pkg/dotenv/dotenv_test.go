@@ -0,0 +1,279 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   string
+		want map[string]string
+	}{
+		{"plain", "A=1\n", map[string]string{"A": "1"}},
+		{"spaces around =", "A = 1 \n", map[string]string{"A": "1"}},
+		{"= in value", "URL=mysql://u:p@host/db?x=y\n", map[string]string{"URL": "mysql://u:p@host/db?x=y"}},
+		{"empty value", "A=\n", map[string]string{"A": ""}},
+		{"single quotes", "A='x # y '\n", map[string]string{"A": "x # y "}},
+		{"single quotes are literal", `A='a\nb $B'` + "\n", map[string]string{"A": `a\nb $B`}},
+		{"double quotes", `A="x # y"` + "\n", map[string]string{"A": "x # y"}},
+		{"escapes", `A="a\nb\tc\"d\\e\$f"` + "\n", map[string]string{"A": "a\nb\tc\"d\\e$f"}},
+		{"unknown escape kept", `A="a\qb"` + "\n", map[string]string{"A": `a\qb`}},
+		{"inline comment", "A=1 # the answer\n", map[string]string{"A": "1"}},
+		{"comment after quotes", `A="1" # the answer` + "\n", map[string]string{"A": "1"}},
+		{"# without space", "A=a#b\n", map[string]string{"A": "a#b"}},
+		{"comments and blank lines", "# a comment\n\n  # indented\nA=1\n", map[string]string{"A": "1"}},
+		{"export prefix", "export A=1\nexport\tB=2\n", map[string]string{"A": "1", "B": "2"}},
+		{"key named export", "export=1\n", map[string]string{"export": "1"}},
+		{"CRLF", "A=1\r\nB=\"2\"\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"no trailing newline", "A=1", map[string]string{"A": "1"}},
+		{"later wins", "A=1\nA=2\n", map[string]string{"A": "2"}},
+		{"multi-line double quotes", "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nB=2\n",
+			map[string]string{"KEY": "-----BEGIN-----\nabc\n-----END-----", "B": "2"}},
+		{"multi-line single quotes", "KEY='a\nb'\n", map[string]string{"KEY": "a\nb"}},
+		{"multi-line CRLF", "KEY=\"a\r\nb\"\r\n", map[string]string{"KEY": "a\nb"}},
+		{"escaped quote does not close", "A=\"a\\\"\nb\"\n", map[string]string{"A": "a\"\nb"}},
+		{"continuation", "A=foo\\\nbar\nB=2\n", map[string]string{"A": "foobar", "B": "2"}},
+		{"continuation over several lines", "A=a\\\nb\\\nc\n", map[string]string{"A": "abc"}},
+		{"escaped backslash ends the value", "A=x\\\\\nB=1\n", map[string]string{"A": `x\\`, "B": "1"}},
+		{"trailing backslash in quotes", `A='C:\dir\'` + "\nB=1\n", map[string]string{"A": `C:\dir\`, "B": "1"}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.name, tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parse(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no =", "A\n", "dotenv:1: invalid line"},
+		{"no key", "=1\n", "dotenv:1: invalid line"},
+		{"key starting with a digit", "1A=1\n", "invalid line"},
+		{"shell code in the key", "X;touch /tmp/pwned;Y=2\n", "invalid line"},
+		{"space in the key", "A B=1\n", "invalid line"},
+		{"unterminated quotes", "A=1\nB=\"open\n", "dotenv:2: unterminated quoted value"},
+		{"continuation into a definition", `WIN_DIR=C:\Users\me\` + "\nDB_HOST=mysql\n", "continues it on line 2"},
+		{"continuation into an exported definition", "A=x\\\nexport B=1\n", "dotenv:1:"},
+		{"missing required variable", "A=${LOADENV_TEST_UNSET:?is required}\n", "LOADENV_TEST_UNSET: is required"},
+		{"unterminated reference", "A=${B\n", "unterminated reference"},
+		{"invalid reference", "A=${1}\n", "invalid reference"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.in))
+		if err == nil {
+			t.Errorf("%s: Parse(%q) succeeded, want an error", tt.name, tt.in)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Parse(%q) = %q, want an error containing %q", tt.name, tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+
+	in := "B=2\nA=1\nB=3\n"
+	want := []Var{{"B", "2"}, {"A", "1"}, {"B", "3"}}
+
+	got, err := Read(strings.NewReader(in), "test", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read(%q) = %v, want the variables in file order %v", in, got, want)
+	}
+
+	// without expansion references are kept as written
+	got, err = Read(strings.NewReader("A=1\nB=${A}\n"), "test", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[1].Value != "${A}" {
+		t.Errorf("Read without expansion: B = %q, want ${A}", got[1].Value)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+
+	in := "# header\n" +
+		"  export A=\"x\" # keep me\n" +
+		"B=1   # aligned\n" +
+		"C='q'#tight\n" +
+		"KEY=\"a\nb\"\n" +
+		"\n"
+
+	lines, err := ReadLines(strings.NewReader(in), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		text      string
+		key       string
+		keyOffset int
+		comment   string
+	}{
+		{"# header", "", 0, ""},
+		{`  export A="x" # keep me`, "A", 9, " # keep me"},
+		{"B=1   # aligned", "B", 0, "   # aligned"},
+		{"C='q'#tight", "C", 0, " #tight"},
+		{"KEY=\"a\nb\"", "KEY", 0, ""},
+		{"", "", 0, ""},
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("ReadLines returned %d lines, want %d", len(lines), len(want))
+	}
+	for i, w := range want {
+		l := lines[i]
+		if l.Text != w.text {
+			t.Errorf("line %d: Text = %q, want %q", i, l.Text, w.text)
+		}
+		if w.key == "" {
+			if l.Var != nil {
+				t.Errorf("line %d: Var = %v, want nil", i, l.Var)
+			}
+			continue
+		}
+		if l.Var == nil || l.Var.Key != w.key {
+			t.Errorf("line %d: Var = %v, want key %s", i, l.Var, w.key)
+			continue
+		}
+		if l.KeyOffset != w.keyOffset {
+			t.Errorf("line %d: KeyOffset = %d, want %d", i, l.KeyOffset, w.keyOffset)
+		}
+		if l.Comment != w.comment {
+			t.Errorf("line %d: Comment = %q, want %q", i, l.Comment, w.comment)
+		}
+	}
+}
+
+// writeFile writes content to a file named name in a temporary directory
+// and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+
+	fname := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fname, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return fname
+}
+
+func TestLoadOverload(t *testing.T) {
+
+	first := writeFile(t, "first.env", "LOADENV_TEST_A=first\nLOADENV_TEST_B=first\n")
+	second := writeFile(t, "second.env", "LOADENV_TEST_B=second\nLOADENV_TEST_C=second\n")
+
+	tests := []struct {
+		name string
+		load func(...string) error
+		want map[string]string
+	}{
+		// Load keeps what is set, including by an earlier file
+		{"Load", Load, map[string]string{
+			"LOADENV_TEST_A": "host",
+			"LOADENV_TEST_B": "first",
+			"LOADENV_TEST_C": "second",
+		}},
+		// Overload replaces it, so later files win
+		{"Overload", Overload, map[string]string{
+			"LOADENV_TEST_A": "first",
+			"LOADENV_TEST_B": "second",
+			"LOADENV_TEST_C": "second",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("LOADENV_TEST_A", "host")
+		for _, key := range []string{"LOADENV_TEST_B", "LOADENV_TEST_C"} {
+			t.Setenv(key, "")
+			os.Unsetenv(key)
+		}
+
+		if err := tt.load(first, second); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for key, want := range tt.want {
+			if got := os.Getenv(key); got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got, want)
+			}
+		}
+	}
+
+	if err := Load(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("Load of a missing file succeeded, want an error")
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+
+	_, err := Parse(strings.NewReader("A=1\nB=${LOADENV_TEST_UNSET:?set it}\n"))
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Name != "dotenv" || parseErr.Line != 2 {
+		t.Errorf("Parse error = %#v, want a *ParseError for dotenv line 2", err)
+	}
+	var resolverErr *ResolverError
+	if !errors.As(err, &resolverErr) || resolverErr.Name != "LOADENV_TEST_UNSET" || resolverErr.Message != "set it" {
+		t.Errorf("Parse error = %#v, want a *ResolverError for LOADENV_TEST_UNSET", err)
+	}
+
+	if _, err := Parse(strings.NewReader("A B=1\n")); !errors.As(err, &parseErr) || errors.As(err, &resolverErr) {
+		t.Errorf("Parse of an invalid line = %#v, want a *ParseError only", err)
+	}
+
+	if err := Load(filepath.Join(t.TempDir(), "missing.env")); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Load of a missing file = %v, want ErrFileNotFound", err)
+	}
+
+	var validationErr *ValidationError
+	err = fmt.Errorf("can not validate: %w", &ValidationError{Key: "PORT", Message: "is required"})
+	if !errors.As(err, &validationErr) || validationErr.Error() != "PORT: is required" {
+		t.Errorf("wrapped validation error = %v, want a *ValidationError for PORT", err)
+	}
+
+	var dockerErr *DockerError
+	err = fmt.Errorf("can not start the stack: %w", &DockerError{Command: "docker compose up", Err: context.DeadlineExceeded})
+	if !errors.As(err, &dockerErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrapped docker error = %v, want a *DockerError wrapping its Err", err)
+	}
+	if got, want := (&DockerError{Command: "docker ps", Stderr: "daemon down", Err: errors.New("exit status 1")}).Error(), "docker ps: daemon down"; got != want {
+		t.Errorf("DockerError.Error() = %q, want %q", got, want)
+	}
+}
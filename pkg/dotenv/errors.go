@@ -0,0 +1,94 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// ErrFileNotFound is what the error of Load and Overload is, with
+// errors.Is, when a file does not exist. It is fs.ErrNotExist.
+var ErrFileNotFound = fs.ErrNotExist
+
+// ParseError is an error in a definition of a dotenv file.
+type ParseError struct {
+	// Name is the name the file was read with.
+	Name string
+	// Line is the number of the first line of the definition.
+	Line int
+	// Err is what is wrong with it, a *ResolverError when a reference
+	// can not be resolved.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Name, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ResolverError is a ${NAME:?message} or ${NAME?message} reference to a
+// variable that is not set.
+type ResolverError struct {
+	// Name is the name of the variable.
+	Name string
+	// Message is the message of the reference, "is not set" if it has
+	// none.
+	Message string
+}
+
+func (e *ResolverError) Error() string {
+	return e.Name + ": " + e.Message
+}
+
+// ValidationError is a variable whose value does not conform to the schema
+// of the environment.
+type ValidationError struct {
+	// Key is the name of the variable.
+	Key string
+	// Message is what is wrong with its value.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// DockerError is a docker or compose command that failed.
+type DockerError struct {
+	// Command is the command line, e.g. "docker compose up -d".
+	Command string
+	// Stderr is what the command wrote to stderr, when it was not
+	// passed on to the user already.
+	Stderr string
+	// Err is why it failed, an *exec.ExitError when it exited with an
+	// error, and context.DeadlineExceeded when it timed out.
+	Err error
+}
+
+func (e *DockerError) Error() string {
+
+	if e.Stderr != "" {
+		return e.Command + ": " + e.Stderr
+	}
+
+	return e.Command + ": " + e.Err.Error()
+}
+
+func (e *DockerError) Unwrap() error {
+	return e.Err
+}
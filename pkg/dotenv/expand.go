@@ -0,0 +1,156 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookupFunc returns the value of a variable and whether it is set.
+type lookupFunc func(name string) (string, bool)
+
+// Expand expands the variable references in s like in an unquoted value,
+// looking up their values with lookup, e.g. os.LookupEnv.
+func Expand(s string, lookup func(name string) (string, bool)) (string, error) {
+	return expandValue(s, lookup)
+}
+
+// expandValue expands the variable references in an unquoted value. \$
+// is a literal dollar.
+func expandValue(s string, lookup lookupFunc) (string, error) {
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i++
+		case s[i] == '$':
+			value, n, err := expandRef(s[i:], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += n - 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// expandRef expands the reference at the start of s, which begins with a
+// $, and returns its value and the number of bytes it took up. $NAME,
+// ${NAME}, ${NAME:-default}, ${NAME-default}, ${NAME:?message} and
+// ${NAME?message} behave like in the shell. A $ not starting a reference
+// is kept.
+func expandRef(s string, lookup lookupFunc) (string, int, error) {
+
+	if len(s) > 1 && s[1] != '{' {
+		n := 1
+		for n < len(s) && isNameByte(s[n], n == 1) {
+			n++
+		}
+		if n == 1 {
+			return "$", 1, nil
+		}
+		value, _ := lookup(s[1:n])
+		return value, n, nil
+	}
+
+	if len(s) < 2 {
+		return "$", 1, nil
+	}
+
+	// find the closing brace, allowing references nested in defaults
+	depth, end := 0, -1
+	for i := 1; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return "", 0, fmt.Errorf("unterminated reference %s", s)
+	}
+
+	body := s[2:end]
+	n := 0
+	for n < len(body) && isNameByte(body[n], n == 0) {
+		n++
+	}
+	if n == 0 {
+		return "", 0, fmt.Errorf("invalid reference ${%s}", body)
+	}
+
+	name, op := body[:n], body[n:]
+	value, set := lookup(name)
+
+	colon := strings.HasPrefix(op, ":")
+	op = strings.TrimPrefix(op, ":")
+	missing := !set || (colon && value == "")
+
+	switch {
+	case op == "":
+		if colon {
+			return "", 0, fmt.Errorf("invalid reference ${%s}", body)
+		}
+		return value, end + 1, nil
+	case op[0] == '-':
+		if missing {
+			def, err := expandValue(op[1:], lookup)
+			return def, end + 1, err
+		}
+		return value, end + 1, nil
+	case op[0] == '?':
+		if missing {
+			msg := op[1:]
+			if msg == "" {
+				msg = "is not set"
+			}
+			return "", 0, &ResolverError{Name: name, Message: msg}
+		}
+		return value, end + 1, nil
+	}
+
+	return "", 0, fmt.Errorf("invalid reference ${%s}", body)
+}
+
+// isNameByte reports whether c can be part of a variable name.
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (!first && c >= '0' && c <= '9')
+}
+
+// isName reports whether s is a variable name, e.g. DB_HOST.
+func isName(s string) bool {
+
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameByte(s[i], i == 0) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dotenv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+
+	t.Setenv("LOADENV_TEST_HOST", "from-host")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bare reference", "A=1\nB=$A\n", "1"},
+		{"braced reference", "A=1\nB=${A}x\n", "1x"},
+		{"in double quotes", "A=1\nB=\"<$A>\"\n", "<1>"},
+		{"not in single quotes", "A=1\nB='$A'\n", "$A"},
+		{"escaped dollar", "A=1\nB=\\$A\n", "$A"},
+		{"escaped dollar in double quotes", "A=1\nB=\"\\$A\"\n", "$A"},
+		{"lone dollar", "B=5$\n", "5$"},
+		{"dollar before a digit", "B=$1\n", "$1"},
+		{"unset", "B=x${LOADENV_TEST_UNSET}y\n", "xy"},
+		{"from the process environment", "B=$LOADENV_TEST_HOST\n", "from-host"},
+		{"file overrides the process environment", "LOADENV_TEST_HOST=file\nB=$LOADENV_TEST_HOST\n", "file"},
+		{"only earlier variables", "B=$A\nA=1\n", ""},
+		{":- when unset", "B=${LOADENV_TEST_UNSET:-def}\n", "def"},
+		{":- when empty", "A=\nB=${A:-def}\n", "def"},
+		{"- when empty", "A=\nB=${A-def}\n", ""},
+		{"- when unset", "B=${LOADENV_TEST_UNSET-def}\n", "def"},
+		{":- when set", "A=1\nB=${A:-def}\n", "1"},
+		{"nested default", "A=1\nB=${LOADENV_TEST_UNSET:-${A}/x}\n", "1/x"},
+		{":? when set", "A=1\nB=${A:?is required}\n", "1"},
+		{"? when empty", "A=\nB=${A?is required}\n", ""},
+	}
+
+	for _, tt := range tests {
+		env, err := Parse(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.name, tt.in, err)
+			continue
+		}
+		if env["B"] != tt.want {
+			t.Errorf("%s: Parse(%q): B = %q, want %q", tt.name, tt.in, env["B"], tt.want)
+		}
+	}
+}
+
+func TestExpandRequired(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"B=${LOADENV_TEST_UNSET:?set it in .env}\n", "LOADENV_TEST_UNSET: set it in .env"},
+		{"A=\nB=${A:?}\n", "A: is not set"},
+		{"B=${LOADENV_TEST_UNSET?}\n", "LOADENV_TEST_UNSET: is not set"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestDialectInterpolation(t *testing.T) {
+
+	in := "A=1\nB=$A\n"
+
+	for name, want := range map[string]string{"posix": "1", "ruby": "1", "node": "$A", "docker": "$A"} {
+		d, err := LookupDialect(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars, err := Read(strings.NewReader(in), name, d, true)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if vars[1].Value != want {
+			t.Errorf("%s: B = %q, want %q", name, vars[1].Value, want)
+		}
+		if d.Interpolates() != (want == "1") {
+			t.Errorf("%s: Interpolates() = %v", name, d.Interpolates())
+		}
+	}
+}
+
+func TestExpandFunc(t *testing.T) {
+
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"USER": "me", "EMPTY": ""}[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"mysql://${USER}@host", "mysql://me@host"},
+		{"${EMPTY:-def}/$USER", "def/me"},
+		{`\$USER`, "$USER"},
+	}
+
+	for _, tt := range tests {
+		got, err := Expand(tt.in, lookup)
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := Expand("${UNSET:?is required}", lookup); err == nil {
+		t.Error("Expand of an unset required reference succeeded, want an error")
+	}
+}
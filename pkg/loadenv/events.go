@@ -0,0 +1,149 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loadenv lets programs that embed loadenv's commands, built with
+// cmd.NewRootCmd, react to the lifecycle of the environment and the stack
+// with Go callbacks.
+package loadenv
+
+import "sync"
+
+// Event is something that happened while a loadenv command ran. It is one
+// of Resolved, BeforeUp, ServiceHealthy and EnvChanged.
+type Event interface {
+	event()
+}
+
+// Resolved is emitted once the environment is loaded, before it is used.
+type Resolved struct {
+	// Files are the dotenv files it was loaded from, in order.
+	Files []string
+	// Vars are the loaded variables by key.
+	Vars map[string]string
+}
+
+// BeforeUp is emitted right before the stack is started.
+type BeforeUp struct {
+	// Project is the compose project of the stack.
+	Project string
+	// Args are the arguments of the compose up command.
+	Args []string
+}
+
+// ServiceHealthy is emitted when a service of the stack becomes ready:
+// running, healthy if it has a health check, and accepting connections.
+// Only up --wait waits for that.
+type ServiceHealthy struct {
+	// Project is the compose project of the stack.
+	Project string
+	// Service is the name of the service.
+	Service string
+}
+
+// EnvChanged is emitted when a command changed the dotenv file.
+type EnvChanged struct {
+	// File is the dotenv file.
+	File string
+	// Keys are the keys set or removed.
+	Keys []string
+}
+
+func (Resolved) event()       {}
+func (BeforeUp) event()       {}
+func (ServiceHealthy) event() {}
+func (EnvChanged) event()     {}
+
+// Events holds the callbacks for the events of a command tree, given to
+// cmd.NewRootCmd in its Options. The zero value has no callbacks, and so
+// does a nil *Events.
+type Events struct {
+	mu        sync.Mutex
+	callbacks []func(Event) error
+}
+
+// OnResolved calls f with the environment once it is loaded. An error
+// stops the command before it uses the environment.
+func (e *Events) OnResolved(f func(Resolved) error) {
+
+	e.On(func(ev Event) error {
+		if ev, ok := ev.(Resolved); ok {
+			return f(ev)
+		}
+		return nil
+	})
+}
+
+// OnBeforeUp calls f right before the stack is started. An error stops
+// the command without starting it.
+func (e *Events) OnBeforeUp(f func(BeforeUp) error) {
+
+	e.On(func(ev Event) error {
+		if ev, ok := ev.(BeforeUp); ok {
+			return f(ev)
+		}
+		return nil
+	})
+}
+
+// OnServiceHealthy calls f for every service that becomes ready. An error
+// fails the wait for the stack.
+func (e *Events) OnServiceHealthy(f func(ServiceHealthy) error) {
+
+	e.On(func(ev Event) error {
+		if ev, ok := ev.(ServiceHealthy); ok {
+			return f(ev)
+		}
+		return nil
+	})
+}
+
+// OnEnvChanged calls f after the dotenv file was changed. An error fails
+// the command, though the file stays changed.
+func (e *Events) OnEnvChanged(f func(EnvChanged) error) {
+
+	e.On(func(ev Event) error {
+		if ev, ok := ev.(EnvChanged); ok {
+			return f(ev)
+		}
+		return nil
+	})
+}
+
+// On calls f with every event, e.g. to pass them on to a plugin.
+func (e *Events) On(f func(Event) error) {
+
+	e.mu.Lock()
+	e.callbacks = append(e.callbacks, f)
+	e.mu.Unlock()
+}
+
+// Emit calls the callbacks for ev in the order they were added, and
+// returns the first error, skipping the callbacks after it.
+func (e *Events) Emit(ev Event) error {
+
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	callbacks := append([]func(Event) error(nil), e.callbacks...)
+	e.mu.Unlock()
+
+	for _, f := range callbacks {
+		if err := f(ev); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
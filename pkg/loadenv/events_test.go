@@ -0,0 +1,57 @@
+// Copyright © 2017 Abdisamad Hashi <shaybix@tuta.io>
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadenv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+
+	var e Events
+	var got []string
+	e.OnResolved(func(ev Resolved) error {
+		got = append(got, "resolved "+ev.Vars["A"])
+		return nil
+	})
+	e.On(func(ev Event) error {
+		got = append(got, reflect.TypeOf(ev).Name())
+		return nil
+	})
+	stop := errors.New("stop")
+	e.OnBeforeUp(func(ev BeforeUp) error {
+		return stop
+	})
+	e.OnBeforeUp(func(ev BeforeUp) error {
+		t.Error("OnBeforeUp callback after a failing one was called")
+		return nil
+	})
+
+	if err := e.Emit(Resolved{Vars: map[string]string{"A": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Emit(BeforeUp{Project: "app"}); err != stop {
+		t.Errorf("Emit(BeforeUp) = %v, want the error of its callback", err)
+	}
+	if want := []string{"resolved 1", "Resolved", "BeforeUp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("callbacks called for %q, want %q", got, want)
+	}
+
+	var none *Events
+	if err := none.Emit(EnvChanged{File: ".env"}); err != nil {
+		t.Errorf("Emit on nil Events = %v, want nil", err)
+	}
+}